@@ -0,0 +1,198 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Artifact is a file stored by a build or job.
+type Artifact struct {
+	Path       string `json:"path"`
+	PrettyPath string `json:"pretty_path,omitempty"`
+	NodeIndex  int    `json:"node_index"`
+	URL        string `json:"url"`
+}
+
+// ListBuildArtifacts fetches the artifacts of the given v1.1 build number.
+//
+// See https://circleci.com/docs/api/v1-reference/#build-artifacts for details
+// on this API action.
+func (client Client) ListBuildArtifacts(vcs string, username string, project string, build string) ([]Artifact, error) {
+	return client.ListBuildArtifactsContext(context.Background(), vcs, username, project, build)
+}
+
+// ListBuildArtifactsContext is like ListBuildArtifacts, but uses the given
+// context for the request.
+func (client Client) ListBuildArtifactsContext(ctx context.Context, vcs string, username string, project string, build string) ([]Artifact, error) {
+	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/:build_num/artifacts
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s/artifacts", base, build)
+
+	var artifacts []Artifact
+	if err := client.request(ctx, "GET", client.v1(path), nil, &artifacts); err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+// ListJobArtifacts fetches the artifacts of the given v2 job number.
+//
+// See https://circleci.com/docs/api/v2/#operation/getJobArtifacts for details
+// on this API action.
+func (client Client) ListJobArtifacts(vcs string, username string, project string, job string) ([]Artifact, error) {
+	return client.ListJobArtifactsContext(context.Background(), vcs, username, project, job)
+}
+
+// ListJobArtifactsContext is like ListJobArtifacts, but uses the given context
+// for the requests.
+func (client Client) ListJobArtifactsContext(ctx context.Context, vcs string, username string, project string, job string) ([]Artifact, error) {
+	// https://circleci.com/api/v2/project/:project-slug/:job-number/artifacts
+	path := fmt.Sprintf("project/%s/%s/artifacts", ProjectSlug(vcs, username, project), job)
+
+	var artifacts []Artifact
+	err := client.paginate(ctx, path, nil, func(items json.RawMessage) (bool, error) {
+		var page []Artifact
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+		artifacts = append(artifacts, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+// OpenArtifact opens the contents of the given artifact for reading, starting
+// at the given byte offset. The offset of the returned reader is also
+// returned, which is zero if the server does not support resuming downloads.
+// If offset is at or beyond the end of the artifact, an empty reader is
+// returned.
+func (client Client) OpenArtifact(artifact Artifact, offset int64) (io.ReadCloser, int64, error) {
+	return client.OpenArtifactContext(context.Background(), artifact, offset)
+}
+
+// OpenArtifactContext is like OpenArtifact, but uses the given context for
+// the request, and for reading the returned body.
+//
+// Since large artifacts can take much longer to download than an API request
+// takes, the timeout of the client's http.Client only applies until the
+// response headers are received. Reading the body is only limited by the
+// context.
+func (client Client) OpenArtifactContext(ctx context.Context, artifact Artifact, offset int64) (io.ReadCloser, int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var timer *time.Timer
+	if client.httpClient != nil && client.httpClient.Timeout > 0 {
+		timer = time.AfterFunc(client.httpClient.Timeout, cancel)
+
+		httpClient := *client.httpClient
+		httpClient.Timeout = 0
+		client.httpClient = &httpClient
+	}
+
+	body, start, err := client.openArtifact(ctx, artifact, offset)
+	if timer != nil && !timer.Stop() && err != nil {
+		err = fmt.Errorf("timed out waiting for artifact %s", artifact.Path)
+	}
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+
+	// The context is released once the body is closed
+	return cancelCloser{body, cancel}, start, nil
+}
+
+// artifactsDomain is the domain that artifacts of the public CircleCI service
+// are served from.
+const artifactsDomain = "circle-artifacts.com"
+
+// artifactHost reports whether the given artifact URL is served by the
+// configured CircleCI host, or by the artifacts domain of the public service,
+// and so needs the API token.
+func (client Client) artifactHost(artifactURL *url.URL) bool {
+	if strings.EqualFold(artifactURL.Host, client.host) {
+		return true
+	}
+
+	if client.host != PublicHostname {
+		return false
+	}
+
+	host := strings.ToLower(artifactURL.Hostname())
+
+	return host == artifactsDomain || strings.HasSuffix(host, "."+artifactsDomain)
+}
+
+// cancelCloser is a reader that cancels a context when it is closed.
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (closer cancelCloser) Close() error {
+	defer closer.cancel()
+	return closer.ReadCloser.Close()
+}
+
+func (client Client) openArtifact(ctx context.Context, artifact Artifact, offset int64) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest("GET", artifact.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Artifacts may be stored with a third party, which must never be sent
+	// the API token
+	send := client.roundTrip
+	if client.artifactHost(req.URL) {
+		send = client.perform
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, 0, nil
+
+	case http.StatusPartialContent:
+		return resp.Body, offset, nil
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The artifact has already been read in full
+		_ = resp.Body.Close()
+		return ioutil.NopCloser(strings.NewReader("")), offset, nil
+
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		apiErr := newAPIError(req, resp, body)
+		apiErr.Message = client.redact(apiErr.Message)
+		return nil, 0, apiErr
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AuthMethod is how the API token is sent with each request.
+type AuthMethod int
+
+const (
+	// AuthHeader sends the API token in the Circle-Token header. This is the
+	// default.
+	AuthHeader AuthMethod = iota
+
+	// AuthBasic sends the API token as the username of HTTP basic auth.
+	AuthBasic
+
+	// AuthQuery sends the API token as the circle-token URL parameter. This
+	// exposes the token to proxy and access logs, and should only be used
+	// with old enterprise instances that do not support the other methods.
+	AuthQuery
+)
+
+// ParseAuthMethod parses the name of an auth method, one of "header",
+// "basic", or "query".
+func ParseAuthMethod(name string) (AuthMethod, error) {
+	switch name {
+	case "header":
+		return AuthHeader, nil
+	case "basic":
+		return AuthBasic, nil
+	case "query":
+		return AuthQuery, nil
+	default:
+		return 0, fmt.Errorf("invalid auth method %q, expected one of header, basic, or query", name)
+	}
+}
+
+// String returns the name of the auth method.
+func (method AuthMethod) String() string {
+	switch method {
+	case AuthHeader:
+		return "header"
+	case AuthBasic:
+		return "basic"
+	case AuthQuery:
+		return "query"
+	default:
+		return fmt.Sprintf("AuthMethod(%d)", int(method))
+	}
+}
+
+// WithAuthMethod returns a copy of the client that sends the API token using
+// the given method.
+func (client Client) WithAuthMethod(method AuthMethod) Client {
+	client.authMethod = method
+	return client
+}
+
+// authenticate adds the API token to the given request.
+func (client Client) authenticate(req *http.Request) {
+	switch client.authMethod {
+	case AuthBasic:
+		req.SetBasicAuth(client.token, "")
+
+	case AuthQuery:
+		// Include the api token as a URL parameter (...?circle-token=xxx)
+		q := req.URL.Query()
+		q.Add("circle-token", client.token)
+		req.URL.RawQuery = q.Encode()
+
+	default:
+		req.Header.Set("Circle-Token", client.token)
+	}
+}
+
+// redact replaces every occurrence of the API token in the given string.
+func (client Client) redact(value string) string {
+	if client.token == "" {
+		return value
+	}
+
+	return strings.Replace(value, client.token, "REDACTED", -1)
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"time"
+)
+
+// Build is a single v1.1 build, as returned by every v1.1 API action in this
+// package.
+//
+// See https://circleci.com/docs/api/v1-reference/#build for details on the
+// fields of a build.
+type Build struct {
+	BuildURL    string `json:"build_url"`
+	BuildNum    int    `json:"build_num"`
+	Username    string `json:"username"`
+	Reponame    string `json:"reponame"`
+	Branch      string `json:"branch"`
+	VCSURL      string `json:"vcs_url"`
+	VCSRevision string `json:"vcs_revision"`
+	VCSTag      string `json:"vcs_tag"`
+	Subject     string `json:"subject"`
+	Body        string `json:"body"`
+
+	CommitterName  string `json:"committer_name"`
+	CommitterEmail string `json:"committer_email"`
+	AuthorName     string `json:"author_name"`
+	AuthorEmail    string `json:"author_email"`
+
+	Status    string `json:"status"`
+	Lifecycle string `json:"lifecycle"`
+	Outcome   string `json:"outcome"`
+	Why       string `json:"why"`
+
+	Canceled           bool `json:"canceled"`
+	Failed             bool `json:"failed"`
+	Timedout           bool `json:"timedout"`
+	InfrastructureFail bool `json:"infrastructure_fail"`
+
+	User      *BuildUser      `json:"user"`
+	Workflows *BuildWorkflows `json:"workflows"`
+
+	QueuedAt        *time.Time `json:"queued_at"`
+	StartTime       *time.Time `json:"start_time"`
+	StopTime        *time.Time `json:"stop_time"`
+	BuildTimeMillis int        `json:"build_time_millis"`
+
+	Parallel        int                    `json:"parallel"`
+	Platform        string                 `json:"platform"`
+	SSHEnabled      bool                   `json:"ssh_enabled"`
+	RetryOf         int                    `json:"retry_of"`
+	Retries         []int                  `json:"retries"`
+	BuildParameters map[string]interface{} `json:"build_parameters"`
+
+	Steps []BuildStep `json:"steps"`
+}
+
+// BuildUser is the user that caused a build to be triggered.
+type BuildUser struct {
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	IsUser    bool   `json:"is_user"`
+	VCSType   string `json:"vcs_type"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// BuildWorkflows describes the workflow job that a build belongs to, if the
+// project uses workflows.
+type BuildWorkflows struct {
+	JobName        string   `json:"job_name"`
+	JobID          string   `json:"job_id"`
+	WorkflowName   string   `json:"workflow_name"`
+	WorkflowID     string   `json:"workflow_id"`
+	WorkspaceID    string   `json:"workspace_id"`
+	UpstreamJobIDs []string `json:"upstream_job_ids"`
+}
+
+// BuildStep is a single step of a build, such as a run command. A step has an
+// action for each parallel node that it ran on.
+type BuildStep struct {
+	Name    string        `json:"name"`
+	Actions []BuildAction `json:"actions"`
+}
+
+// BuildAction is a single step of a build, as run on a single parallel node.
+type BuildAction struct {
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	Index         int        `json:"index"`
+	Step          int        `json:"step"`
+	Status        string     `json:"status"`
+	ExitCode      *int       `json:"exit_code"`
+	Failed        bool       `json:"failed"`
+	Timedout      bool       `json:"timedout"`
+	Canceled      bool       `json:"canceled"`
+	Truncated     bool       `json:"truncated"`
+	BashCommand   string     `json:"bash_command"`
+	HasOutput     bool       `json:"has_output"`
+	OutputURL     string     `json:"output_url"`
+	StartTime     *time.Time `json:"start_time"`
+	EndTime       *time.Time `json:"end_time"`
+	RunTimeMillis int        `json:"run_time_millis"`
+}
+
+// Finished reports whether the action has stopped running, and its output
+// is complete.
+func (action BuildAction) Finished() bool {
+	switch action.Status {
+	case "", "running", "queued":
+		return false
+	default:
+		return true
+	}
+}
+
+// BuildResponse is the previous name of Build.
+//
+// Deprecated: use Build instead.
+type BuildResponse = Build
+
+// Finished reports whether the build has reached a terminal lifecycle, and
+// will not change status again.
+func (build Build) Finished() bool {
+	switch build.Lifecycle {
+	case "finished", "not_run":
+		return true
+	default:
+		return false
+	}
+}
@@ -6,11 +6,17 @@ package cci
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -19,20 +25,47 @@ const (
 )
 
 type Client struct {
-	token string
-	host  string
-}
-
-type BuildResponse struct {
-	BuildURL string `json:"build_url"`
+	token       string
+	host        string
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	authMethod  AuthMethod
+	logger      Logger
 }
 
 func New(token string) Client {
-	return Client{token, PublicHostname}
+	return Client{token: token, host: PublicHostname}
 }
 
 func NewWithHost(token string, host string) Client {
-	return Client{token, host}
+	return Client{token: token, host: host}
+}
+
+// WithHTTPClient returns a copy of the client that performs requests using the
+// given HTTP client. This can be used to configure timeouts and proxies, or to
+// direct requests at a test server.
+func (client Client) WithHTTPClient(httpClient *http.Client) Client {
+	client.httpClient = httpClient
+	return client
+}
+
+// WithTransport returns a copy of the client that performs requests using the
+// given round tripper. Any timeout already configured on the client's HTTP
+// client is preserved.
+func (client Client) WithTransport(transport http.RoundTripper) Client {
+	httpClient := &http.Client{Transport: transport}
+	if client.httpClient != nil {
+		httpClient.Timeout = client.httpClient.Timeout
+	}
+	client.httpClient = httpClient
+	return client
+}
+
+// WithRetryPolicy returns a copy of the client that retries failed requests
+// according to the given policy. By default, requests are never retried.
+func (client Client) WithRetryPolicy(policy RetryPolicy) Client {
+	client.retryPolicy = policy
+	return client
 }
 
 // BuildDefault triggers a build on the HEAD of the default branch. This branch
@@ -40,82 +73,295 @@ func NewWithHost(token string, host string) Client {
 //
 // See https://circleci.com/docs/api/v1-reference/#new-build for details on
 // this API action.
-func (client Client) BuildDefault(vcs string, username string, project string, params map[string]string) (*BuildResponse, error) {
+func (client Client) BuildDefault(vcs string, username string, project string, params map[string]string) (*Build, error) {
+	return client.BuildDefaultContext(context.Background(), vcs, username, project, params)
+}
+
+// BuildDefaultContext is like BuildDefault, but uses the given context for the
+// request.
+func (client Client) BuildDefaultContext(ctx context.Context, vcs string, username string, project string, params map[string]string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project
-	path := fmt.Sprintf("project/%s/%s/%s", vcs, username, project)
+	path, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
 
-	return client.do(path, "", "", params)
+	return client.do(ctx, path, "", "", params)
 }
 
 // BuildTag triggers a build on the given tag.
 //
 // See https://circleci.com/docs/api/v1-reference/#new-build for details on
 // this API action.
-func (client Client) BuildTag(vcs string, username string, project string, tag string, params map[string]string) (*BuildResponse, error) {
+func (client Client) BuildTag(vcs string, username string, project string, tag string, params map[string]string) (*Build, error) {
+	return client.BuildTagContext(context.Background(), vcs, username, project, tag, params)
+}
+
+// BuildTagContext is like BuildTag, but uses the given context for the
+// request.
+func (client Client) BuildTagContext(ctx context.Context, vcs string, username string, project string, tag string, params map[string]string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project
-	path := fmt.Sprintf("project/%s/%s/%s", vcs, username, project)
+	path, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
 
-	return client.do(path, tag, "", params)
+	return client.do(ctx, path, tag, "", params)
 }
 
 // BuildRef triggers a build on the given ref.
 //
 // See https://circleci.com/docs/api/v1-reference/#new-build for details on
 // this API action.
-func (client Client) BuildRef(vcs string, username string, project string, ref string, params map[string]string) (*BuildResponse, error) {
+func (client Client) BuildRef(vcs string, username string, project string, ref string, params map[string]string) (*Build, error) {
+	return client.BuildRefContext(context.Background(), vcs, username, project, ref, params)
+}
+
+// BuildRefContext is like BuildRef, but uses the given context for the
+// request.
+func (client Client) BuildRefContext(ctx context.Context, vcs string, username string, project string, ref string, params map[string]string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project
-	path := fmt.Sprintf("project/%s/%s/%s", vcs, username, project)
+	path, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
 
-	return client.do(path, "", ref, params)
+	return client.do(ctx, path, "", ref, params)
 }
 
 // BuildBranch triggers a build on the HEAD of the given branch.
 //
 // See https://circleci.com/docs/api/v1-reference/#new-build-branch for details
 // on this API action.
-func (client Client) BuildBranch(vcs string, username string, project string, branch string, params map[string]string) (*BuildResponse, error) {
+func (client Client) BuildBranch(vcs string, username string, project string, branch string, params map[string]string) (*Build, error) {
+	return client.BuildBranchContext(context.Background(), vcs, username, project, branch, params)
+}
+
+// BuildBranchContext is like BuildBranch, but uses the given context for the
+// request.
+func (client Client) BuildBranchContext(ctx context.Context, vcs string, username string, project string, branch string, params map[string]string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/tree/:branch
-	path := fmt.Sprintf("project/%s/%s/%s/tree/%s", vcs, username, project, branch)
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/tree/%s", base, branch)
 
-	return client.do(path, "", "", params)
+	return client.do(ctx, path, "", "", params)
 }
 
 // BuildBranchAtRef triggers a build on the given branch at the given ref.
 //
 // See https://circleci.com/docs/api/v1-reference/#new-build-branch for details
 // on this API action.
-func (client Client) BuildBranchAtRef(vcs string, username string, project string, branch string, ref string, params map[string]string) (*BuildResponse, error) {
+func (client Client) BuildBranchAtRef(vcs string, username string, project string, branch string, ref string, params map[string]string) (*Build, error) {
+	return client.BuildBranchAtRefContext(context.Background(), vcs, username, project, branch, ref, params)
+}
+
+// BuildBranchAtRefContext is like BuildBranchAtRef, but uses the given context
+// for the request.
+func (client Client) BuildBranchAtRefContext(ctx context.Context, vcs string, username string, project string, branch string, ref string, params map[string]string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/tree/:branch
-	path := fmt.Sprintf("project/%s/%s/%s/tree/%s", vcs, username, project, branch)
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/tree/%s", base, branch)
+
+	return client.do(ctx, path, "", ref, params)
+}
+
+// GetBuild fetches the current state of the given build number.
+//
+// See https://circleci.com/docs/api/v1-reference/#build for details on this
+// API action.
+func (client Client) GetBuild(vcs string, username string, project string, build string) (*Build, error) {
+	return client.GetBuildContext(context.Background(), vcs, username, project, build)
+}
+
+// GetBuildContext is like GetBuild, but uses the given context for the
+// request.
+func (client Client) GetBuildContext(ctx context.Context, vcs string, username string, project string, build string) (*Build, error) {
+	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/:build_num
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s", base, build)
+
+	var result Build
+	if err := client.request(ctx, "GET", client.v1(path), nil, &result); err != nil {
+		return nil, err
+	}
 
-	return client.do(path, "", ref, params)
+	return &result, nil
 }
 
 // Rebuild triggers a rebuild on the given build number.
 //
 // See https://circleci.com/docs/api/v1-reference/#retry-build for details on
 // this API action.
-func (client Client) Rebuild(vcs string, username string, project string, build string) (*BuildResponse, error) {
+func (client Client) Rebuild(vcs string, username string, project string, build string) (*Build, error) {
+	return client.RebuildContext(context.Background(), vcs, username, project, build)
+}
+
+// RebuildContext is like Rebuild, but uses the given context for the request.
+func (client Client) RebuildContext(ctx context.Context, vcs string, username string, project string, build string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/:build_num/retry
-	path := fmt.Sprintf("project/%s/%s/%s/%s/retry", vcs, username, project, build)
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s/retry", base, build)
 
-	return client.do(path, "", "", nil)
+	return client.do(ctx, path, "", "", nil)
 }
 
 // RebuildWithSSH triggers a rebuild on the given build number, and enables SSH.
 //
 // See https://circleci.com/docs/api/v1-reference/#retry-build for details on
 // this API action.
-func (client Client) RebuildWithSSH(vcs string, username string, project string, build string) (*BuildResponse, error) {
+func (client Client) RebuildWithSSH(vcs string, username string, project string, build string) (*Build, error) {
+	return client.RebuildWithSSHContext(context.Background(), vcs, username, project, build)
+}
+
+// RebuildWithSSHContext is like RebuildWithSSH, but uses the given context for
+// the request.
+func (client Client) RebuildWithSSHContext(ctx context.Context, vcs string, username string, project string, build string) (*Build, error) {
 	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/:build_num/ssh
-	path := fmt.Sprintf("project/%s/%s/%s/%s/ssh", vcs, username, project, build)
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s/ssh", base, build)
+
+	return client.do(ctx, path, "", "", nil)
+}
+
+// CancelBuild cancels the given build number.
+//
+// See https://circleci.com/docs/api/v1-reference/#cancel-build for details on
+// this API action.
+func (client Client) CancelBuild(vcs string, username string, project string, build string) (*Build, error) {
+	return client.CancelBuildContext(context.Background(), vcs, username, project, build)
+}
+
+// CancelBuildContext is like CancelBuild, but uses the given context for the
+// request.
+func (client Client) CancelBuildContext(ctx context.Context, vcs string, username string, project string, build string) (*Build, error) {
+	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/:build_num/cancel
+	base, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s/cancel", base, build)
 
-	return client.do(path, "", "", nil)
+	return client.do(ctx, path, "", "", nil)
 }
 
-func (client Client) do(path string, tag string, revision string, buildParams map[string]string) (*BuildResponse, error) {
+// FilterPageLimit is the maximum number of pages fetched when results are
+// filtered after being fetched, so that a filter matching few or no results
+// does not page through the entire history of a project.
+const FilterPageLimit = 10
+
+// ListBuildsOptions filters the builds returned by ListBuilds.
+type ListBuildsOptions struct {
+	// Branch limits the builds to those on the given branch.
+	Branch string
+
+	// Filter limits the builds to those with the given status, one of
+	// "completed", "successful", "failed", or "running".
+	Filter string
+
+	// User limits the builds to those triggered by the user with the given
+	// login. The API does not support this filter, so builds are filtered
+	// after being fetched, and only the most recent FilterPageLimit pages of
+	// builds are searched.
+	User string
+
+	// Limit is the maximum number of builds to return. If zero, 30 builds are
+	// returned.
+	Limit int
+}
 
-	url := fmt.Sprintf("https://%s/api/v1.1/%s", client.host, path)
+// ListBuilds fetches the most recent builds of the given project, newest
+// first. Multiple requests are made if needed to satisfy the limit.
+//
+// See https://circleci.com/docs/api/v1-reference/#recent-builds-project for
+// details on this API action.
+func (client Client) ListBuilds(vcs string, username string, project string, options ListBuildsOptions) ([]Build, error) {
+	return client.ListBuildsContext(context.Background(), vcs, username, project, options)
+}
+
+// ListBuildsContext is like ListBuilds, but uses the given context for the
+// requests.
+func (client Client) ListBuildsContext(ctx context.Context, vcs string, username string, project string, options ListBuildsOptions) ([]Build, error) {
+	// The API will return at most this many builds per request
+	const pageSize = 100
+
+	// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project
+	path, err := v1Project(vcs, username, project)
+	if err != nil {
+		return nil, err
+	}
+	if options.Branch != "" {
+		// https://circleci.com/api/v1.1/project/:vcs-type/:username/:project/tree/:branch
+		path = fmt.Sprintf("%s/tree/%s", path, options.Branch)
+	}
+
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 30
+	}
+
+	var (
+		builds = make([]Build, 0, limit)
+		offset int
+	)
+
+	for pages := 0; len(builds) < limit; pages++ {
+		if options.User != "" && pages == FilterPageLimit {
+			break
+		}
+
+		count := limit - len(builds)
+		if count > pageSize || options.User != "" {
+			count = pageSize
+		}
+
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(count))
+		query.Set("offset", strconv.Itoa(offset))
+		if options.Filter != "" {
+			query.Set("filter", options.Filter)
+		}
+
+		var page []Build
+		if err := client.request(ctx, "GET", client.v1(path)+"?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+
+		offset += len(page)
+
+		for _, build := range page {
+			if options.User != "" && (build.User == nil || build.User.Login != options.User) {
+				continue
+			}
+			if len(builds) < limit {
+				builds = append(builds, build)
+			}
+		}
+
+		// A short page means there are no more builds
+		if len(page) < count {
+			break
+		}
+	}
+
+	return builds, nil
+}
+
+func (client Client) do(ctx context.Context, path string, tag string, revision string, buildParams map[string]string) (*Build, error) {
 
 	var postParams = struct {
 		Tag         string            `json:"tag,omitempty"`
@@ -127,29 +373,189 @@ func (client Client) do(path string, tag string, revision string, buildParams ma
 		buildParams,
 	}
 
-	postBody, err := json.Marshal(postParams)
-	if err != nil {
+	var result Build
+	if err := client.request(ctx, "POST", client.v1(path), postParams, &result); err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(postBody))
+	return &result, nil
+}
+
+// v1 returns the full URL for the given v1.1 API path.
+func (client Client) v1(path string) string {
+	return fmt.Sprintf("https://%s/api/v1.1/%s", client.host, path)
+}
+
+// v1Project returns the v1.1 API path for the given project. The v1.1 API
+// only supports GitHub and Bitbucket projects, so an UnsupportedVCSError is
+// returned for any other VCS.
+func v1Project(vcs string, username string, project string) (string, error) {
+	switch vcs {
+	case "github", "bitbucket":
+		return fmt.Sprintf("project/%s/%s/%s", vcs, username, project), nil
+	default:
+		return "", &UnsupportedVCSError{VCS: vcs}
+	}
+}
+
+// v2 returns the full URL for the given v2 API path.
+func (client Client) v2(path string) string {
+	return fmt.Sprintf("https://%s/api/v2/%s", client.host, path)
+}
+
+// request sends the given payload (if any) as JSON to the given endpoint, and
+// decodes the JSON response into result.
+func (client Client) request(ctx context.Context, method string, endpoint string, payload interface{}, result interface{}) error {
+	var (
+		body []byte
+		err  error
+	)
+
+	if payload != nil {
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.send(ctx, method, endpoint, body)
 	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(resp, result)
+}
+
+// send performs an HTTP request against the given endpoint, retrying failed
+// attempts as permitted by the client's retry policy. The response body is
+// returned if the request was ultimately successful.
+func (client Client) send(ctx context.Context, method string, endpoint string, payload []byte) ([]byte, error) {
+	policy := client.retryPolicy
+
+	for attempt := 1; ; attempt++ {
+		body, resp, err := client.attempt(ctx, method, endpoint, payload)
+		if err == nil {
+			return body, nil
+		}
+
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(method, resp) {
+			return nil, err
+		}
+
+		wait, ok := policy.delay(attempt, resp)
+		if !ok {
+			return nil, err
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, wait, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// perform authenticates and performs the given HTTP request. Any transport error
+// has the API token redacted from it.
+func (client Client) perform(req *http.Request) (*http.Response, error) {
+	client.authenticate(req)
+
+	return client.roundTrip(req)
+}
+
+// roundTrip sends the given request as is, without adding the api token. This
+// is used directly for requests to third party hosts, such as pre-signed
+// storage URLs, which must never be sent the token. Redirects to a different
+// host have every header that could carry the token removed, as the http
+// package does not know about the Circle-Token header.
+func (client Client) roundTrip(req *http.Request) (*http.Response, error) {
+	httpClient := client.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	checkRedirect := httpClient.CheckRedirect
+	redirectClient := *httpClient
+	redirectClient.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		// The Referer would carry a token sent as a query parameter, and the
+		// http package keeps Authorization for other ports on the same host
+		if !strings.EqualFold(next.URL.Host, via[0].URL.Host) {
+			next.Header.Del("Circle-Token")
+			next.Header.Del("Authorization")
+			next.Header.Del("Referer")
+		}
+
+		if checkRedirect != nil {
+			return checkRedirect(next, via)
+		}
+
+		// The same limit as the http package's default policy
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		return nil
+	}
+	httpClient = &redirectClient
+
+	var entry RequestLog
+	if client.logger != nil {
+		entry = client.newRequestLog(req)
+	}
+
+	start := time.Now()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// Transport errors embed the request URL, so scrub the token from it
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = redactURL(req.URL)
+		}
+
+		if client.logger != nil {
+			entry.Duration = time.Since(start)
+			entry.Err = err
+			client.logger(entry)
+		}
+
 		return nil, err
 	}
 
+	if client.logger != nil {
+		client.logResponse(entry, resp, start)
+	}
+
+	return resp, nil
+}
+
+// attempt performs a single HTTP request against the given endpoint. The
+// response is returned alongside any error so that the caller can decide
+// whether to retry.
+func (client Client) attempt(ctx context.Context, method string, endpoint string, payload []byte) ([]byte, *http.Response, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+
 	// Indicate that we are sending (and want to receive) JSON
-	req.Header.Set("Content-Type", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Accept", "application/json")
 
-	// Include the api token as a URL parameter (...?circle-token=xxx)
-	q := req.URL.Query()
-	q.Add("circle-token", client.token)
-	req.URL.RawQuery = q.Encode()
-
-	// Perform the HTTP POST
-	resp, err := http.DefaultClient.Do(req)
+	// Perform the HTTP request
+	resp, err := client.perform(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Cleanup function
@@ -159,21 +565,18 @@ func (client Client) do(path string, tag string, revision string, buildParams ma
 		}
 	}()
 
-	// Return error if request was not "successful"
-	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
-		return nil, errors.New(resp.Status)
-	}
-
 	// Read the entire response body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
 
-	var br BuildResponse
-	if err := json.Unmarshal(body, &br); err != nil {
-		return nil, err
+	// Return error if request was not "successful"
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		apiErr := newAPIError(req, resp, body)
+		apiErr.Message = client.redact(apiErr.Message)
+		return nil, resp, apiErr
 	}
 
-	return &br, nil
+	return body, resp, nil
 }
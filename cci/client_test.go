@@ -0,0 +1,1096 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient starts a TLS test server using the given handler, and returns
+// a client that is configured to talk to it.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (Client, func()) {
+	server := httptest.NewTLSServer(handler)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	client := NewWithHost("token", host).WithHTTPClient(server.Client())
+
+	return client, server.Close
+}
+
+func TestClientActions(t *testing.T) {
+
+	tests := []struct {
+		title  string
+		action func(Client) (*Build, error)
+		path   string
+		body   string
+	}{
+		{
+			title: "build default",
+			action: func(client Client) (*Build, error) {
+				return client.BuildDefault("github", "alice", "example", nil)
+			},
+			path: "/api/v1.1/project/github/alice/example",
+			body: `{}`,
+		},
+		{
+			title: "build tag",
+			action: func(client Client) (*Build, error) {
+				return client.BuildTag("github", "alice", "example", "v1.0.0", nil)
+			},
+			path: "/api/v1.1/project/github/alice/example",
+			body: `{"tag":"v1.0.0"}`,
+		},
+		{
+			title: "build ref",
+			action: func(client Client) (*Build, error) {
+				return client.BuildRef("github", "alice", "example", "abc123", map[string]string{"key": "value"})
+			},
+			path: "/api/v1.1/project/github/alice/example",
+			body: `{"revision":"abc123","build_parameters":{"key":"value"}}`,
+		},
+		{
+			title: "build branch",
+			action: func(client Client) (*Build, error) {
+				return client.BuildBranch("bitbucket", "bob", "example", "develop", nil)
+			},
+			path: "/api/v1.1/project/bitbucket/bob/example/tree/develop",
+			body: `{}`,
+		},
+		{
+			title: "build branch at ref",
+			action: func(client Client) (*Build, error) {
+				return client.BuildBranchAtRef("bitbucket", "bob", "example", "develop", "abc123", nil)
+			},
+			path: "/api/v1.1/project/bitbucket/bob/example/tree/develop",
+			body: `{"revision":"abc123"}`,
+		},
+		{
+			title: "rebuild",
+			action: func(client Client) (*Build, error) {
+				return client.Rebuild("github", "carol", "example", "123")
+			},
+			path: "/api/v1.1/project/github/carol/example/123/retry",
+			body: `{}`,
+		},
+		{
+			title: "rebuild with ssh",
+			action: func(client Client) (*Build, error) {
+				return client.RebuildWithSSH("github", "carol", "example", "123")
+			},
+			path: "/api/v1.1/project/github/carol/example/123/ssh",
+			body: `{}`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				require.Equal(t, "POST", r.Method)
+				require.Equal(t, test.path, r.URL.Path)
+				require.JSONEq(t, test.body, string(body))
+
+				json.NewEncoder(w).Encode(map[string]string{
+					"build_url": "https://circleci.com/gh/alice/example/1",
+				})
+			})
+			defer done()
+
+			resp, err := test.action(client)
+			require.NoError(t, err)
+			require.Equal(t, "https://circleci.com/gh/alice/example/1", resp.BuildURL)
+		})
+	}
+}
+
+func TestClientContextCanceled(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not have been sent")
+	})
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.BuildDefaultContext(ctx, "github", "alice", "example", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), context.Canceled.Error())
+}
+
+func TestClientAPIError(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		status  int
+		body    string
+		err     string
+		checker func(error) bool
+	}{
+		{
+			title:   "not found with message",
+			status:  http.StatusNotFound,
+			body:    `{"message": "Project not found"}`,
+			err:     "404 Not Found: Project not found",
+			checker: IsNotFound,
+		},
+		{
+			title:   "unauthorized with message",
+			status:  http.StatusUnauthorized,
+			body:    `{"message": "You must log in first."}`,
+			err:     "401 Unauthorized: You must log in first.",
+			checker: IsUnauthorized,
+		},
+		{
+			title:   "forbidden without body",
+			status:  http.StatusForbidden,
+			err:     "403 Forbidden",
+			checker: IsForbidden,
+		},
+		{
+			title:   "rate limited with non-json body",
+			status:  http.StatusTooManyRequests,
+			body:    `slow down`,
+			err:     "429 Too Many Requests",
+			checker: IsRateLimited,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, test.body)
+			})
+			defer done()
+
+			_, err := client.BuildDefault("github", "alice", "example", nil)
+			require.Error(t, err)
+			require.True(t, test.checker(err))
+
+			apiErr, ok := err.(*APIError)
+			require.True(t, ok)
+			require.Equal(t, test.status, apiErr.StatusCode)
+			require.Equal(t, "POST", apiErr.Method)
+			require.NotContains(t, apiErr.URL, "token=token")
+			require.True(t, strings.HasSuffix(err.Error(), test.err))
+		})
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+
+	tests := []struct {
+		title      string
+		statuses   []int
+		retryAfter string
+		attempts   int
+		err        bool
+	}{
+		{
+			title:    "success without retry",
+			statuses: []int{http.StatusOK},
+			attempts: 1,
+		},
+		{
+			title:    "rate limited then success",
+			statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK},
+			attempts: 3,
+		},
+		{
+			title:    "unavailable then success",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			attempts: 2,
+		},
+		{
+			title:    "attempts exhausted",
+			statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK},
+			attempts: 3,
+			err:      true,
+		},
+		{
+			title:    "internal error not retried for post",
+			statuses: []int{http.StatusInternalServerError, http.StatusOK},
+			attempts: 1,
+			err:      true,
+		},
+		{
+			title:    "not found not retried",
+			statuses: []int{http.StatusNotFound, http.StatusOK},
+			attempts: 1,
+			err:      true,
+		},
+		{
+			title:      "retry after honored",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "0",
+			attempts:   2,
+		},
+		{
+			title:      "retry after too long",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "3600",
+			attempts:   1,
+			err:        true,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var attempts int
+
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[attempts]
+				attempts++
+
+				if test.retryAfter != "" {
+					w.Header().Set("Retry-After", test.retryAfter)
+				}
+				w.WriteHeader(status)
+				fmt.Fprint(w, `{}`)
+			})
+			defer done()
+
+			client = client.WithRetryPolicy(RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				MaxWait:     time.Second,
+			})
+
+			_, err := client.BuildDefault("github", "alice", "example", nil)
+			if test.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, test.attempts, attempts)
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		policy   RetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		{
+			title:    "first retry",
+			policy:   RetryPolicy{Backoff: time.Second},
+			attempt:  1,
+			expected: time.Second,
+		},
+		{
+			title:    "doubled",
+			policy:   RetryPolicy{Backoff: time.Second},
+			attempt:  4,
+			expected: 8 * time.Second,
+		},
+		{
+			title:    "max wait",
+			policy:   RetryPolicy{Backoff: time.Second, MaxWait: 5 * time.Second},
+			attempt:  4,
+			expected: 5 * time.Second,
+		},
+		{
+			title:    "large attempt",
+			policy:   RetryPolicy{Backoff: time.Second, MaxWait: 5 * time.Second},
+			attempt:  100,
+			expected: 5 * time.Second,
+		},
+		{
+			title:    "large attempt without max wait",
+			policy:   RetryPolicy{Backoff: time.Second},
+			attempt:  100,
+			expected: maxBackoff,
+		},
+		{
+			title:    "large attempt with jitter",
+			policy:   RetryPolicy{Backoff: time.Second, Jitter: 1},
+			attempt:  1000,
+			expected: maxBackoff,
+		},
+		{
+			title:    "no backoff",
+			policy:   RetryPolicy{},
+			attempt:  100,
+			expected: 0,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			wait, retry := test.policy.delay(test.attempt, nil)
+			require.True(t, retry)
+
+			// Jitter only ever extends the wait, by up to its fraction
+			require.True(t, wait >= test.expected, "wait %s is shorter than %s", wait, test.expected)
+			require.True(t, wait <= test.expected+time.Duration(test.policy.Jitter*float64(test.expected)), "wait %s is too long", wait)
+		})
+	}
+}
+
+func TestClientGetBuild(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v1.1/project/github/alice/example/123", r.URL.Path)
+
+		fmt.Fprint(w, `{
+			"build_url": "https://circleci.com/gh/alice/example/123",
+			"build_num": 123,
+			"branch": "master",
+			"vcs_revision": "2c9bd6df0a3e4b7d81f6c7cb7b0fce5d2c4a1d3e",
+			"status": "success",
+			"lifecycle": "finished",
+			"outcome": "success",
+			"why": "retry",
+			"user": {"login": "alice", "is_user": true},
+			"queued_at": "2017-10-01T12:00:00.000Z",
+			"stop_time": null,
+			"workflows": {"job_name": "build", "workflow_id": "cd6e0a7b"}
+		}`)
+	})
+	defer done()
+
+	resp, err := client.GetBuild("github", "alice", "example", "123")
+	require.NoError(t, err)
+	require.Equal(t, 123, resp.BuildNum)
+	require.Equal(t, "success", resp.Status)
+	require.Equal(t, "success", resp.Outcome)
+	require.Equal(t, "master", resp.Branch)
+	require.Equal(t, "2c9bd6df0a3e4b7d81f6c7cb7b0fce5d2c4a1d3e", resp.VCSRevision)
+	require.Equal(t, "alice", resp.User.Login)
+	require.Equal(t, "build", resp.Workflows.JobName)
+	require.Equal(t, time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC), *resp.QueuedAt)
+	require.Nil(t, resp.StopTime)
+	require.True(t, resp.Finished())
+}
+
+func TestClientTriggerPipeline(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/api/v2/project/gh/alice/example/pipeline", r.URL.Path)
+		require.JSONEq(t, `{"branch":"develop","parameters":{"deploy":true,"count":3,"env":"staging"}}`, string(body))
+
+		fmt.Fprint(w, `{
+			"id": "5034460f-c7c4-4c43-9457-de07e2029e7b",
+			"state": "pending",
+			"number": 25,
+			"created_at": "2019-08-24T14:15:22Z"
+		}`)
+	})
+	defer done()
+
+	pipeline, err := client.TriggerPipeline("github", "alice", "example", TriggerPipelineOptions{
+		Branch: "develop",
+		Parameters: map[string]interface{}{
+			"deploy": true,
+			"count":  3,
+			"env":    "staging",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "5034460f-c7c4-4c43-9457-de07e2029e7b", pipeline.ID)
+	require.Equal(t, 25, pipeline.Number)
+	require.Equal(t, "pending", pipeline.State)
+}
+
+func TestClientGetWorkflowJobsPaginated(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v2/workflow/fda08377/job", r.URL.Path)
+
+		switch r.URL.Query().Get("page-token") {
+		case "":
+			fmt.Fprint(w, `{"items": [{"name": "compile"}, {"name": "test"}], "next_page_token": "page2"}`)
+		case "page2":
+			fmt.Fprint(w, `{"items": [{"name": "deploy"}], "next_page_token": null}`)
+		default:
+			t.Fatalf("unexpected page token %q", r.URL.Query().Get("page-token"))
+		}
+	})
+	defer done()
+
+	jobs, err := client.GetWorkflowJobs("fda08377")
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	require.Equal(t, "compile", jobs[0].Name)
+	require.Equal(t, "deploy", jobs[2].Name)
+}
+
+func TestClientListBuildsPaginated(t *testing.T) {
+	var offsets []string
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v1.1/project/github/alice/example/tree/master", r.URL.Path)
+		require.Equal(t, "running", r.URL.Query().Get("filter"))
+
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+
+		// Pretend that there are 150 running builds in total
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		builds := make([]Build, 0, limit)
+		for num := offset; num < offset+limit && num < 150; num++ {
+			builds = append(builds, Build{BuildNum: num})
+		}
+		json.NewEncoder(w).Encode(builds)
+	})
+	defer done()
+
+	builds, err := client.ListBuilds("github", "alice", "example", ListBuildsOptions{
+		Branch: "master",
+		Filter: "running",
+		Limit:  250,
+	})
+	require.NoError(t, err)
+	require.Len(t, builds, 150)
+	require.Equal(t, []string{"0", "100"}, offsets)
+}
+
+func TestClientGetActionOutput(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/output/step/0", r.URL.Path)
+		require.Empty(t, r.URL.Query().Get("circle-token"))
+
+		fmt.Fprint(w, `[
+			{"type": "out", "time": "2017-10-01T12:00:00.000Z", "message": "hello\r\n"},
+			{"type": "err", "time": "2017-10-01T12:00:01.000Z", "message": "world\r\n"}
+		]`)
+	})
+	defer done()
+
+	action := BuildAction{
+		HasOutput: true,
+		OutputURL: fmt.Sprintf("https://%s/output/step/0", client.host),
+	}
+
+	output, err := client.GetActionOutput(action)
+	require.NoError(t, err)
+	require.Len(t, output, 2)
+	require.Equal(t, "out", output[0].Type)
+	require.Equal(t, "hello\r\n", output[0].Message)
+	require.Equal(t, "err", output[1].Type)
+
+	// Actions without any output are not fetched
+	output, err = client.GetActionOutput(BuildAction{OutputURL: action.OutputURL})
+	require.NoError(t, err)
+	require.Empty(t, output)
+}
+
+func TestClientApproveJob(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/api/v2/workflow/w1/approve/r1", r.URL.Path)
+
+		fmt.Fprint(w, `{"message": "Accepted."}`)
+	})
+	defer done()
+
+	require.NoError(t, client.ApproveJob("w1", "r1"))
+}
+
+func TestClientRerunWorkflow(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		options RerunWorkflowOptions
+		body    string
+		err     string
+	}{
+		{
+			title: "entire workflow",
+			body:  `{}`,
+		},
+		{
+			title:   "from failed with ssh",
+			options: RerunWorkflowOptions{FromFailed: true, EnableSSH: true},
+			body:    `{"from_failed": true, "enable_ssh": true}`,
+		},
+		{
+			title:   "sparse tree of jobs",
+			options: RerunWorkflowOptions{Jobs: []string{"j1", "j2"}, SparseTree: true},
+			body:    `{"jobs": ["j1", "j2"], "sparse_tree": true}`,
+		},
+		{
+			title:   "from failed with jobs",
+			options: RerunWorkflowOptions{FromFailed: true, Jobs: []string{"j1"}},
+			err:     "only one of from failed or jobs may be given",
+		},
+		{
+			title:   "sparse tree without jobs",
+			options: RerunWorkflowOptions{SparseTree: true},
+			err:     "sparse tree requires jobs to be given",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				require.Equal(t, "POST", r.Method)
+				require.Equal(t, "/api/v2/workflow/w1/rerun", r.URL.Path)
+				require.JSONEq(t, test.body, string(body))
+
+				fmt.Fprint(w, `{"workflow_id": "w2"}`)
+			})
+			defer done()
+
+			id, err := client.RerunWorkflow("w1", test.options)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "w2", id)
+		})
+	}
+}
+
+func TestClientListBuildsByUser(t *testing.T) {
+	var offsets []string
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1.1/project/github/alice/example", r.URL.Path)
+		require.Equal(t, "100", r.URL.Query().Get("limit"))
+
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+
+		// Pretend that there are 150 builds in total, every third of which
+		// was triggered by bob
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		builds := make([]Build, 0, 100)
+		for num := offset; num < offset+100 && num < 150; num++ {
+			login := "alice"
+			if num%3 == 0 {
+				login = "bob"
+			}
+			builds = append(builds, Build{BuildNum: num, User: &BuildUser{Login: login}})
+		}
+		json.NewEncoder(w).Encode(builds)
+	})
+	defer done()
+
+	builds, err := client.ListBuilds("github", "alice", "example", ListBuildsOptions{
+		User:  "bob",
+		Limit: 40,
+	})
+	require.NoError(t, err)
+	require.Len(t, builds, 40)
+	require.Equal(t, 117, builds[39].BuildNum)
+	require.Equal(t, []string{"0", "100"}, offsets)
+}
+
+func TestClientListBuildsFilterPageLimit(t *testing.T) {
+	var requests int
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// Every page is full, and none of the builds match
+		builds := make([]Build, 100)
+		for index := range builds {
+			builds[index] = Build{BuildNum: index, User: &BuildUser{Login: "alice"}}
+		}
+		json.NewEncoder(w).Encode(builds)
+	})
+	defer done()
+
+	builds, err := client.ListBuilds("github", "alice", "example", ListBuildsOptions{
+		User: "bob",
+	})
+	require.NoError(t, err)
+	require.Empty(t, builds)
+	require.Equal(t, FilterPageLimit, requests)
+}
+
+func TestClientListPipelines(t *testing.T) {
+	var pages int
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v2/project/gh/alice/example/pipeline", r.URL.Path)
+		require.Equal(t, "master", r.URL.Query().Get("branch"))
+
+		pages++
+		switch r.URL.Query().Get("page-token") {
+		case "":
+			fmt.Fprint(w, `{"items": [{"number": 3, "state": "created"}, {"number": 2, "state": "errored"}], "next_page_token": "next"}`)
+		case "next":
+			fmt.Fprint(w, `{"items": [{"number": 1, "state": "created"}, {"number": 0, "state": "created"}], "next_page_token": "last"}`)
+		default:
+			t.Fatal("pagination should have stopped once the limit was reached")
+		}
+	})
+	defer done()
+
+	pipelines, err := client.ListPipelines("github", "alice", "example", ListPipelinesOptions{
+		Branch: "master",
+		State:  "created",
+		Limit:  2,
+	})
+	require.NoError(t, err)
+	require.Len(t, pipelines, 2)
+	require.Equal(t, 3, pipelines[0].Number)
+	require.Equal(t, 1, pipelines[1].Number)
+	require.Equal(t, 2, pages)
+}
+
+func TestClientListPipelinesFilterPageLimit(t *testing.T) {
+	var requests int
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// There is always another page, and none of the pipelines match
+		fmt.Fprintf(w, `{"items": [{"number": %d, "state": "created"}], "next_page_token": "page%d"}`, requests, requests)
+	})
+	defer done()
+
+	pipelines, err := client.ListPipelines("github", "alice", "example", ListPipelinesOptions{
+		State: "errored",
+	})
+	require.NoError(t, err)
+	require.Empty(t, pipelines)
+	require.Equal(t, FilterPageLimit, requests)
+}
+
+func TestClientAuthMethods(t *testing.T) {
+
+	tests := []struct {
+		title  string
+		method AuthMethod
+		check  func(*testing.T, *http.Request)
+	}{
+		{
+			title:  "header",
+			method: AuthHeader,
+			check: func(t *testing.T, r *http.Request) {
+				require.Equal(t, "secret", r.Header.Get("Circle-Token"))
+				require.Empty(t, r.URL.RawQuery)
+			},
+		},
+		{
+			title:  "basic",
+			method: AuthBasic,
+			check: func(t *testing.T, r *http.Request) {
+				username, password, ok := r.BasicAuth()
+				require.True(t, ok)
+				require.Equal(t, "secret", username)
+				require.Empty(t, password)
+				require.Empty(t, r.Header.Get("Circle-Token"))
+			},
+		},
+		{
+			title:  "query",
+			method: AuthQuery,
+			check: func(t *testing.T, r *http.Request) {
+				require.Equal(t, "secret", r.URL.Query().Get("circle-token"))
+				require.Empty(t, r.Header.Get("Circle-Token"))
+			},
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				test.check(t, r)
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"message": "token secret is not valid"}`)
+			})
+			defer done()
+
+			client.token = "secret"
+
+			_, err := client.WithAuthMethod(test.method).GetBuild("github", "alice", "example", "123")
+			require.Error(t, err)
+			require.True(t, IsUnauthorized(err))
+			require.NotContains(t, err.Error(), "secret")
+		})
+	}
+}
+
+func TestParseAuthMethod(t *testing.T) {
+	for _, method := range []AuthMethod{AuthHeader, AuthBasic, AuthQuery} {
+		parsed, err := ParseAuthMethod(method.String())
+		require.NoError(t, err)
+		require.Equal(t, method, parsed)
+	}
+
+	_, err := ParseAuthMethod("cookie")
+	require.Error(t, err)
+}
+
+func TestProjectSlug(t *testing.T) {
+
+	tests := []struct {
+		title string
+		vcs   string
+		slug  string
+	}{
+		{
+			title: "github",
+			vcs:   "github",
+			slug:  "gh/alice/example",
+		},
+		{
+			title: "bitbucket",
+			vcs:   "bitbucket",
+			slug:  "bb/alice/example",
+		},
+		{
+			title: "gitlab",
+			vcs:   "gitlab",
+			slug:  "gl/alice/example",
+		},
+		{
+			title: "standalone",
+			vcs:   "circleci",
+			slug:  "circleci/alice/example",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.slug, ProjectSlug(test.vcs, "alice", "example"))
+		})
+	}
+}
+
+func TestClientUnsupportedVCS(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not have been sent")
+	})
+	defer done()
+
+	for _, vcs := range []string{"gitlab", "circleci"} {
+		_, err := client.Rebuild(vcs, "alice", "example", "123")
+		require.EqualError(t, err, fmt.Sprintf("%s projects are not supported by the v1.1 API", vcs))
+		require.True(t, IsUnsupportedVCS(err))
+
+		_, err = client.BuildBranch(vcs, "alice", "example", "master", nil)
+		require.True(t, IsUnsupportedVCS(err))
+
+		_, err = client.ListBuilds(vcs, "alice", "example", ListBuildsOptions{})
+		require.True(t, IsUnsupportedVCS(err))
+	}
+}
+
+func TestClientListProjects(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v1.1/projects", r.URL.Path)
+
+		fmt.Fprint(w, `[
+			{"vcs_type": "github", "vcs_url": "https://github.com/alice/api", "username": "alice", "reponame": "api", "following": true},
+			{"vcs_url": "https://bitbucket.org/alice/web", "username": "alice", "reponame": "web", "following": true}
+		]`)
+	})
+	defer done()
+
+	projects, err := client.ListProjects()
+	require.NoError(t, err)
+	require.Equal(t, []Project{
+		{VCSType: "github", VCSURL: "https://github.com/alice/api", Username: "alice", Reponame: "api", Following: true},
+		{VCSType: "bitbucket", VCSURL: "https://bitbucket.org/alice/web", Username: "alice", Reponame: "web", Following: true},
+	}, projects)
+}
+
+func TestClientOpenArtifact(t *testing.T) {
+	const contents = "artifact contents"
+
+	tests := []struct {
+		title    string
+		offset   int64
+		expected string
+		start    int64
+	}{
+		{
+			title:    "full download",
+			expected: contents,
+		},
+		{
+			title:    "resumed download",
+			offset:   9,
+			expected: "contents",
+			start:    9,
+		},
+		{
+			title:  "already complete",
+			offset: int64(len(contents)),
+			start:  int64(len(contents)),
+		},
+	}
+
+	for index, test := range tests {
+		t.Logf("Case #%d - %s", index, test.title)
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "artifact.txt", time.Time{}, strings.NewReader(contents))
+		}))
+
+		artifact := Artifact{Path: "artifact.txt", URL: server.URL + "/artifact.txt"}
+		client := NewWithHost("token", strings.TrimPrefix(server.URL, "https://")).WithHTTPClient(server.Client())
+
+		body, start, err := client.OpenArtifact(artifact, test.offset)
+		require.NoError(t, err)
+
+		actual, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		require.NoError(t, body.Close())
+
+		require.Equal(t, test.start, start)
+		require.Equal(t, test.expected, string(actual))
+
+		server.Close()
+	}
+}
+
+func TestClientOpenArtifactToken(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		auth     AuthMethod
+		redirect bool
+	}{
+		{
+			title:    "header auth redirected to storage",
+			auth:     AuthHeader,
+			redirect: true,
+		},
+		{
+			title:    "basic auth redirected to storage",
+			auth:     AuthBasic,
+			redirect: true,
+		},
+		{
+			title:    "query auth redirected to storage",
+			auth:     AuthQuery,
+			redirect: true,
+		},
+		{
+			title: "header auth on storage",
+			auth:  AuthHeader,
+		},
+		{
+			title: "query auth on storage",
+			auth:  AuthQuery,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			// The storage server is a third party, which must never see the token
+			storage := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, _, _ := r.BasicAuth()
+
+				require.Empty(t, r.Header.Get("Circle-Token"))
+				require.Empty(t, r.URL.Query().Get("circle-token"))
+				require.Empty(t, username)
+				require.NotContains(t, fmt.Sprint(r.Header, r.URL), "token")
+
+				fmt.Fprint(w, "artifact contents")
+			}))
+			defer storage.Close()
+
+			var authenticated bool
+
+			circle := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, _, _ := r.BasicAuth()
+				authenticated = r.Header.Get("Circle-Token") == "token" || r.URL.Query().Get("circle-token") == "token" || username == "token"
+
+				http.Redirect(w, r, storage.URL+"/signed/artifact.txt?signature=abc", http.StatusFound)
+			}))
+			defer circle.Close()
+
+			client := NewWithHost("token", strings.TrimPrefix(circle.URL, "https://")).
+				WithHTTPClient(circle.Client()).
+				WithAuthMethod(test.auth)
+
+			artifact := Artifact{Path: "artifact.txt", URL: storage.URL + "/artifact.txt"}
+			if test.redirect {
+				artifact.URL = circle.URL + "/artifact.txt"
+			}
+
+			body, _, err := client.OpenArtifact(artifact, 0)
+			require.NoError(t, err)
+
+			actual, err := ioutil.ReadAll(body)
+			require.NoError(t, err)
+			require.NoError(t, body.Close())
+
+			require.Equal(t, "artifact contents", string(actual))
+			require.Equal(t, test.redirect, authenticated)
+		})
+	}
+}
+
+func TestClientArtifactHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		url      string
+		expected bool
+	}{
+		{host: PublicHostname, url: "https://circleci.com/api/v1.1/project/github/alice/example/1/artifacts/0/a.txt", expected: true},
+		{host: PublicHostname, url: "https://123-456-gh.circle-artifacts.com/0/a.txt", expected: true},
+		{host: PublicHostname, url: "https://output.circle-artifacts.com/output/job/1/artifacts/0/a.txt", expected: true},
+		{host: PublicHostname, url: "https://bucket.s3.amazonaws.com/a.txt"},
+		{host: PublicHostname, url: "https://circle-artifacts.com.example.com/a.txt"},
+		{host: "circleci.example.com", url: "https://circleci.example.com/0/a.txt", expected: true},
+		{host: "circleci.example.com", url: "https://123-456-gh.circle-artifacts.com/0/a.txt"},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.url)
+
+		t.Run(name, func(t *testing.T) {
+			artifactURL, err := url.Parse(test.url)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, NewWithHost("token", test.host).artifactHost(artifactURL))
+		})
+	}
+}
+
+func TestClientOpenArtifactTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	tests := []struct {
+		title       string
+		headerDelay time.Duration
+		bodyDelay   time.Duration
+		errMessage  string
+	}{
+		{
+			title:     "body slower than the timeout",
+			bodyDelay: 3 * timeout,
+		},
+		{
+			title:       "headers slower than the timeout",
+			headerDelay: 3 * timeout,
+			errMessage:  "timed out waiting for artifact artifact.txt",
+		},
+	}
+
+	for index, test := range tests {
+		t.Logf("Case #%d - %s", index, test.title)
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(test.headerDelay)
+			fmt.Fprint(w, "artifact ")
+			w.(http.Flusher).Flush()
+			time.Sleep(test.bodyDelay)
+			fmt.Fprint(w, "contents")
+		}))
+
+		httpClient := &http.Client{Transport: server.Client().Transport, Timeout: timeout}
+		artifact := Artifact{Path: "artifact.txt", URL: server.URL + "/artifact.txt"}
+		client := NewWithHost("token", strings.TrimPrefix(server.URL, "https://")).WithHTTPClient(httpClient)
+
+		body, _, err := client.OpenArtifact(artifact, 0)
+		if test.errMessage != "" {
+			require.EqualError(t, err, test.errMessage)
+			server.Close()
+			continue
+		}
+		require.NoError(t, err)
+
+		actual, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		require.NoError(t, body.Close())
+		require.Equal(t, "artifact contents", string(actual))
+
+		server.Close()
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	// Enough projects that the response body is truncated in the log
+	projects := make([]Project, 100)
+	for index := range projects {
+		projects[index] = Project{VCSType: "github", Username: "alice", Reponame: fmt.Sprintf("project-%d", index), Following: true}
+	}
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc123")
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"build_num": 1, "message": "triggered with token"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(projects)
+	})
+	defer done()
+
+	var entries []RequestLog
+	client = client.WithLogger(func(entry RequestLog) {
+		entries = append(entries, entry)
+	})
+
+	actual, err := client.WithAuthMethod(AuthBasic).ListProjects()
+	require.NoError(t, err)
+	require.Equal(t, projects, actual)
+
+	_, err = client.WithAuthMethod(AuthQuery).BuildBranch("github", "alice", "example", "master", map[string]string{"SECRET": "token"})
+	require.NoError(t, err)
+
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "GET", entries[0].Method)
+	require.Equal(t, "REDACTED", entries[0].RequestHeader.Get("Authorization"))
+	require.Equal(t, 200, entries[0].StatusCode)
+	require.Equal(t, "abc123", entries[0].ResponseHeader.Get("X-Request-Id"))
+	require.Len(t, entries[0].ResponseBody, LogBodyLimit)
+	require.True(t, entries[0].ResponseBodyTruncated)
+
+	require.Equal(t, "POST", entries[1].Method)
+	require.True(t, strings.HasSuffix(entries[1].URL, "/api/v1.1/project/github/alice/example/tree/master?circle-token=REDACTED"))
+	require.Equal(t, `{"build_parameters":{"SECRET":"REDACTED"}}`, string(entries[1].RequestBody))
+	require.Equal(t, "201 Created", entries[1].Status)
+	require.Equal(t, `{"build_num": 1, "message": "triggered with REDACTED"}`, string(entries[1].ResponseBody))
+	require.False(t, entries[1].ResponseBodyTruncated)
+	require.NoError(t, entries[1].Err)
+}
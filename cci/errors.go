@@ -0,0 +1,117 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// APIError is returned when the CircleCI API responds with a non-successful
+// status code.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the request URL, with the API token redacted.
+	URL string
+
+	// Message is the error message from the CircleCI response body, if one
+	// was given.
+	Message string
+}
+
+func (err *APIError) Error() string {
+	status := fmt.Sprintf("%d %s", err.StatusCode, http.StatusText(err.StatusCode))
+
+	if err.Message == "" {
+		return fmt.Sprintf("%s %s: %s", err.Method, err.URL, status)
+	}
+
+	return fmt.Sprintf("%s %s: %s: %s", err.Method, err.URL, status, err.Message)
+}
+
+// IsNotFound reports whether the given error is an APIError caused by a
+// missing project, branch, or build. CircleCI also responds this way for
+// projects that are not followed.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether the given error is an APIError caused by a
+// missing or invalid API token.
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether the given error is an APIError caused by an API
+// token that lacks permission for the requested action.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether the given error is an APIError caused by
+// exceeding the CircleCI API rate limit.
+func IsRateLimited(err error) bool {
+	return hasStatusCode(err, http.StatusTooManyRequests)
+}
+
+// UnsupportedVCSError is returned when a v1.1 API action is used with a
+// project whose VCS is only supported by the v2 API, such as GitLab.
+type UnsupportedVCSError struct {
+	// VCS is the VCS of the project.
+	VCS string
+}
+
+func (err *UnsupportedVCSError) Error() string {
+	return fmt.Sprintf("%s projects are not supported by the v1.1 API", err.VCS)
+}
+
+// IsUnsupportedVCS reports whether the given error is an UnsupportedVCSError.
+func IsUnsupportedVCS(err error) bool {
+	_, ok := err.(*UnsupportedVCSError)
+	return ok
+}
+
+func hasStatusCode(err error, code int) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == code
+}
+
+// newAPIError builds an APIError from a failed request, and the body of its
+// response.
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	// CircleCI error responses look like {"message": "..."}, but the body is
+	// not guaranteed to be JSON, so failures here are ignored
+	var payload struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &payload)
+
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		URL:        redactURL(req.URL),
+		Message:    payload.Message,
+	}
+}
+
+// redactURL renders the given URL with the value of any API token replaced.
+func redactURL(u *url.URL) string {
+	redacted := *u
+
+	q := redacted.Query()
+	if q.Get("circle-token") != "" {
+		q.Set("circle-token", "REDACTED")
+		redacted.RawQuery = q.Encode()
+	}
+
+	return redacted.String()
+}
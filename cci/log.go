@@ -0,0 +1,149 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// LogBodyLimit is the maximum number of bytes of each request and response
+// body included in a RequestLog.
+const LogBodyLimit = 4096
+
+// RequestLog describes a single HTTP request made by the client, and its
+// response. The API token is redacted from every field.
+type RequestLog struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the request URL.
+	URL string
+
+	// RequestHeader is the header of the request.
+	RequestHeader http.Header
+
+	// RequestBody is the start of the request body, if there was one.
+	RequestBody []byte
+
+	// RequestBodyTruncated is true if RequestBody is only part of the body.
+	RequestBodyTruncated bool
+
+	// Status is the status of the response, such as "201 Created", or empty
+	// if no response was received.
+	Status string
+
+	// StatusCode is the HTTP status code of the response, or 0 if no response
+	// was received.
+	StatusCode int
+
+	// ResponseHeader is the header of the response.
+	ResponseHeader http.Header
+
+	// ResponseBody is the start of the response body.
+	ResponseBody []byte
+
+	// ResponseBodyTruncated is true if ResponseBody is only part of the body.
+	ResponseBodyTruncated bool
+
+	// Duration is how long it took to receive the response, or the error.
+	Duration time.Duration
+
+	// Err is the error that prevented a response from being received, if any.
+	Err error
+}
+
+// Logger is called with the details of each HTTP request made by a client,
+// once the response or error has been received. A logger may be called from
+// multiple goroutines at once.
+type Logger func(entry RequestLog)
+
+// WithLogger returns a copy of the client that calls the given logger for
+// every HTTP request it makes, including each retry. By default, requests
+// are not logged.
+func (client Client) WithLogger(logger Logger) Client {
+	client.logger = logger
+	return client
+}
+
+// newRequestLog starts a log entry for the given request, which is about to
+// be sent.
+func (client Client) newRequestLog(req *http.Request) RequestLog {
+	entry := RequestLog{
+		Method:        req.Method,
+		URL:           redactURL(req.URL),
+		RequestHeader: client.redactHeader(req.Header),
+	}
+
+	// Reading a copy of the body leaves the request itself untouched
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			entry.RequestBody, entry.RequestBodyTruncated = client.peek(body)
+			body.Close()
+		}
+	}
+
+	return entry
+}
+
+// logResponse completes the given log entry with the given response, and
+// passes it to the logger. The start of the response body is read for the
+// log, and then put back so that the caller can still read all of it.
+func (client Client) logResponse(entry RequestLog, resp *http.Response, start time.Time) {
+	entry.Duration = time.Since(start)
+	entry.Status = resp.Status
+	entry.StatusCode = resp.StatusCode
+	entry.ResponseHeader = client.redactHeader(resp.Header)
+
+	peeked, err := ioutil.ReadAll(io.LimitReader(resp.Body, LogBodyLimit+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+
+	if err == nil {
+		entry.ResponseBody, entry.ResponseBodyTruncated = client.peek(bytes.NewReader(peeked))
+	}
+
+	client.logger(entry)
+}
+
+// peek reads up to LogBodyLimit bytes from the given reader, with the API
+// token redacted, and reports whether there was more to read.
+func (client Client) peek(r io.Reader) ([]byte, bool) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, LogBodyLimit+1))
+	if err != nil {
+		return nil, false
+	}
+
+	truncated := len(body) > LogBodyLimit
+	if truncated {
+		body = body[:LogBodyLimit]
+	}
+
+	return []byte(client.redact(string(body))), truncated
+}
+
+// redactHeader returns a copy of the given header, with the API token
+// redacted from every value. Since basic auth encodes the token, the
+// Authorization header is redacted entirely.
+func (client Client) redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+
+	for name, values := range header {
+		for _, value := range values {
+			redacted.Add(name, client.redact(value))
+		}
+	}
+
+	if redacted.Get("Authorization") != "" {
+		redacted.Set("Authorization", "REDACTED")
+	}
+
+	return redacted
+}
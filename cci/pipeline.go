@@ -0,0 +1,247 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Pipeline is a single v2 pipeline.
+//
+// See https://circleci.com/docs/api/v2/#tag/Pipeline for details on the fields
+// of a pipeline.
+type Pipeline struct {
+	ID          string           `json:"id"`
+	ProjectSlug string           `json:"project_slug"`
+	Number      int              `json:"number"`
+	State       string           `json:"state"`
+	CreatedAt   *time.Time       `json:"created_at"`
+	UpdatedAt   *time.Time       `json:"updated_at"`
+	Errors      []PipelineError  `json:"errors"`
+	Trigger     *PipelineTrigger `json:"trigger"`
+	VCS         *PipelineVCS     `json:"vcs"`
+}
+
+// PipelineError is an error encountered while setting up a pipeline, such as
+// an invalid config file.
+type PipelineError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// PipelineTrigger describes what caused a pipeline to be created.
+type PipelineTrigger struct {
+	Type       string     `json:"type"`
+	ReceivedAt *time.Time `json:"received_at"`
+	Actor      struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"actor"`
+}
+
+// PipelineVCS describes the commit that a pipeline was created for.
+type PipelineVCS struct {
+	ProviderName        string `json:"provider_name"`
+	OriginRepositoryURL string `json:"origin_repository_url"`
+	Branch              string `json:"branch"`
+	Tag                 string `json:"tag"`
+	Revision            string `json:"revision"`
+}
+
+// TriggerPipelineOptions configures a new pipeline. At most one of Branch and
+// Tag may be given, and the default branch is used if neither is.
+type TriggerPipelineOptions struct {
+	Branch string `json:"branch,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+
+	// Parameters are the pipeline parameters declared by the project config.
+	// Values must be a string, bool, or int.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ListPipelinesOptions filters the pipelines returned by ListPipelines.
+type ListPipelinesOptions struct {
+	// Branch limits the pipelines to those on the given branch.
+	Branch string
+
+	// State limits the pipelines to those in the given state, such as
+	// "created" or "errored". The API does not support this filter, so
+	// pipelines are filtered after being fetched, and only the most recent
+	// FilterPageLimit pages of pipelines are searched.
+	State string
+
+	// User limits the pipelines to those triggered by the user with the given
+	// login. As with State, pipelines are filtered after being fetched.
+	User string
+
+	// Limit is the maximum number of pipelines to return. If zero, 30
+	// pipelines are returned.
+	Limit int
+}
+
+// ProjectSlug returns the v2 project slug for the given v1.1 style project,
+// such as "gh/username/project". The vcs may also be "gitlab", or "circleci"
+// for standalone projects, which are identified by their organization and
+// project IDs.
+func ProjectSlug(vcs string, username string, project string) string {
+	switch vcs {
+	case "github":
+		vcs = "gh"
+	case "bitbucket":
+		vcs = "bb"
+	case "gitlab":
+		vcs = "gl"
+	}
+
+	return fmt.Sprintf("%s/%s/%s", vcs, username, project)
+}
+
+// TriggerPipeline triggers a new pipeline on the given project.
+//
+// See https://circleci.com/docs/api/v2/#operation/triggerPipeline for details
+// on this API action.
+func (client Client) TriggerPipeline(vcs string, username string, project string, options TriggerPipelineOptions) (*Pipeline, error) {
+	return client.TriggerPipelineContext(context.Background(), vcs, username, project, options)
+}
+
+// TriggerPipelineContext is like TriggerPipeline, but uses the given context
+// for the request.
+func (client Client) TriggerPipelineContext(ctx context.Context, vcs string, username string, project string, options TriggerPipelineOptions) (*Pipeline, error) {
+	if options.Branch != "" && options.Tag != "" {
+		return nil, errors.New("only one of branch or tag may be given")
+	}
+
+	for name, value := range options.Parameters {
+		switch value.(type) {
+		case string, bool, int:
+		default:
+			return nil, fmt.Errorf("pipeline parameter %q has unsupported type %T", name, value)
+		}
+	}
+
+	// https://circleci.com/api/v2/project/:project-slug/pipeline
+	path := fmt.Sprintf("project/%s/pipeline", ProjectSlug(vcs, username, project))
+
+	var result Pipeline
+	if err := client.request(ctx, "POST", client.v2(path), options, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetPipeline fetches the pipeline with the given ID.
+//
+// See https://circleci.com/docs/api/v2/#operation/getPipelineById for details
+// on this API action.
+func (client Client) GetPipeline(id string) (*Pipeline, error) {
+	return client.GetPipelineContext(context.Background(), id)
+}
+
+// GetPipelineContext is like GetPipeline, but uses the given context for the
+// request.
+func (client Client) GetPipelineContext(ctx context.Context, id string) (*Pipeline, error) {
+	// https://circleci.com/api/v2/pipeline/:pipeline-id
+	path := fmt.Sprintf("pipeline/%s", url.PathEscape(id))
+
+	var result Pipeline
+	if err := client.request(ctx, "GET", client.v2(path), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetPipelineByNumber fetches the pipeline with the given number on the given
+// project.
+//
+// See https://circleci.com/docs/api/v2/#operation/getPipelineByNumber for
+// details on this API action.
+func (client Client) GetPipelineByNumber(vcs string, username string, project string, number string) (*Pipeline, error) {
+	return client.GetPipelineByNumberContext(context.Background(), vcs, username, project, number)
+}
+
+// GetPipelineByNumberContext is like GetPipelineByNumber, but uses the given
+// context for the request.
+func (client Client) GetPipelineByNumberContext(ctx context.Context, vcs string, username string, project string, number string) (*Pipeline, error) {
+	// https://circleci.com/api/v2/project/:project-slug/pipeline/:pipeline-number
+	path := fmt.Sprintf("project/%s/pipeline/%s", ProjectSlug(vcs, username, project), number)
+
+	var result Pipeline
+	if err := client.request(ctx, "GET", client.v2(path), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ListPipelines fetches the most recent pipelines of the given project,
+// newest first. Multiple requests are made if needed to satisfy the limit.
+//
+// See https://circleci.com/docs/api/v2/#operation/listPipelinesForProject for
+// details on this API action.
+func (client Client) ListPipelines(vcs string, username string, project string, options ListPipelinesOptions) ([]Pipeline, error) {
+	return client.ListPipelinesContext(context.Background(), vcs, username, project, options)
+}
+
+// ListPipelinesContext is like ListPipelines, but uses the given context for
+// the requests.
+func (client Client) ListPipelinesContext(ctx context.Context, vcs string, username string, project string, options ListPipelinesOptions) ([]Pipeline, error) {
+	// https://circleci.com/api/v2/project/:project-slug/pipeline
+	path := fmt.Sprintf("project/%s/pipeline", ProjectSlug(vcs, username, project))
+
+	query := url.Values{}
+	if options.Branch != "" {
+		query.Set("branch", options.Branch)
+	}
+
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 30
+	}
+
+	var (
+		pipelines = make([]Pipeline, 0, limit)
+		filtered  = options.State != "" || options.User != ""
+		pages     int
+	)
+
+	err := client.paginate(ctx, path, query, func(items json.RawMessage) (bool, error) {
+		pages++
+
+		var page []Pipeline
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+
+		for _, pipeline := range page {
+			if options.State != "" && pipeline.State != options.State {
+				continue
+			}
+			if options.User != "" && (pipeline.Trigger == nil || pipeline.Trigger.Actor.Login != options.User) {
+				continue
+			}
+			if len(pipelines) < limit {
+				pipelines = append(pipelines, pipeline)
+			}
+		}
+
+		if filtered && pages == FilterPageLimit {
+			return false, nil
+		}
+
+		return len(pipelines) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pipelines, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"strings"
+)
+
+// Project is a project followed by the user that owns the API token.
+type Project struct {
+	VCSType   string `json:"vcs_type"`
+	VCSURL    string `json:"vcs_url"`
+	Username  string `json:"username"`
+	Reponame  string `json:"reponame"`
+	Following bool   `json:"following"`
+}
+
+// ListProjects fetches every project followed by the user that owns the API
+// token.
+//
+// See https://circleci.com/docs/api/v1-reference/#projects for details on this
+// API action.
+func (client Client) ListProjects() ([]Project, error) {
+	return client.ListProjectsContext(context.Background())
+}
+
+// ListProjectsContext is like ListProjects, but uses the given context for
+// the request.
+func (client Client) ListProjectsContext(ctx context.Context) ([]Project, error) {
+	// https://circleci.com/api/v1.1/projects
+	var projects []Project
+	if err := client.request(ctx, "GET", client.v1("projects"), nil, &projects); err != nil {
+		return nil, err
+	}
+
+	// Older CircleCI instances do not include the VCS type, so it is found
+	// from the VCS URL instead
+	for index, project := range projects {
+		if project.VCSType != "" {
+			continue
+		}
+		switch {
+		case strings.Contains(project.VCSURL, "github.com"):
+			projects[index].VCSType = "github"
+		case strings.Contains(project.VCSURL, "bitbucket.org"):
+			projects[index].VCSType = "bitbucket"
+		}
+	}
+
+	return projects, nil
+}
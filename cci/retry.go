@@ -0,0 +1,136 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+//
+// Rate limited (429) and unavailable (503) responses are always retried, as
+// CircleCI has not acted on the request. Other server errors and network
+// failures are only retried for idempotent requests, such as fetching build
+// details, so that a build is never triggered twice.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for each request,
+	// including the first one. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// Backoff is the time to wait before the first retry. The wait is doubled
+	// for each subsequent retry.
+	Backoff time.Duration
+
+	// MaxWait is the longest time to wait between attempts. If the server
+	// asks for a longer wait with a Retry-After header, the request is not
+	// retried. A zero value means no limit on Retry-After, while the backoff
+	// is still limited to an hour.
+	MaxWait time.Duration
+
+	// Jitter randomly extends each wait by up to the given fraction of its
+	// length, so that many clients do not retry in lockstep.
+	Jitter float64
+
+	// OnRetry, if set, is called before waiting to retry a failed attempt.
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// maxBackoff is the longest time to wait between attempts when the policy
+// does not set a MaxWait.
+const maxBackoff = time.Hour
+
+// delay returns how long to wait before retrying after the given attempt,
+// and whether a retry should happen at all.
+func (policy RetryPolicy) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	// Honor the server's requested wait, if any
+	if wait, ok := retryAfter(resp); ok {
+		if policy.MaxWait > 0 && wait > policy.MaxWait {
+			return 0, false
+		}
+		return wait, true
+	}
+
+	limit := policy.MaxWait
+	if limit <= 0 {
+		limit = maxBackoff
+	}
+
+	// The wait is doubled one step at a time and capped, rather than shifted
+	// by the attempt number, so that it can never overflow
+	wait := policy.Backoff
+	for retry := 1; retry < attempt && wait > 0 && wait < limit; retry++ {
+		if wait > limit/2 {
+			wait = limit
+			break
+		}
+		wait *= 2
+	}
+	if wait > limit {
+		wait = limit
+	}
+
+	if policy.Jitter > 0 {
+		wait += time.Duration(rand.Float64() * policy.Jitter * float64(wait))
+	}
+
+	if policy.MaxWait > 0 && wait > policy.MaxWait {
+		wait = policy.MaxWait
+	}
+
+	return wait, true
+}
+
+// retryAfter parses the Retry-After header of the given response, which may
+// be either a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// retryable reports whether a request with the given method, that failed
+// with the given response, is safe to retry. A nil response indicates that
+// the request failed at the network level.
+func retryable(method string, resp *http.Response) bool {
+	// The server did not act on the request, so any method can be retried
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true
+		}
+	}
+
+	// The request may or may not have been acted on, so only retry it if
+	// doing so twice would be harmless
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		return false
+	}
+
+	return resp == nil || resp.StatusCode >= 500
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ActionOutput is a single chunk of output written by a build action.
+type ActionOutput struct {
+	Type    string     `json:"type"`
+	Time    *time.Time `json:"time"`
+	Message string     `json:"message"`
+}
+
+// GetActionOutput fetches the output written by the given build action. No
+// output is returned for actions that have not written any, or that are
+// still running.
+//
+// The output of an action is hosted outside of the CircleCI API, so the API
+// token is not sent with this request.
+func (client Client) GetActionOutput(action BuildAction) ([]ActionOutput, error) {
+	return client.GetActionOutputContext(context.Background(), action)
+}
+
+// GetActionOutputContext is like GetActionOutput, but uses the given context
+// for the request.
+func (client Client) GetActionOutputContext(ctx context.Context, action BuildAction) ([]ActionOutput, error) {
+	if !action.HasOutput || action.OutputURL == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequest("GET", action.OutputURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := client.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return nil, newAPIError(req, resp, body)
+	}
+
+	var output []ActionOutput
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Workflow is a single v2 workflow, belonging to a pipeline.
+//
+// See https://circleci.com/docs/api/v2/#tag/Workflow for details on the fields
+// of a workflow.
+type Workflow struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	PipelineID     string     `json:"pipeline_id"`
+	PipelineNumber int        `json:"pipeline_number"`
+	ProjectSlug    string     `json:"project_slug"`
+	StartedBy      string     `json:"started_by"`
+	CanceledBy     string     `json:"canceled_by,omitempty"`
+	ErroredBy      string     `json:"errored_by,omitempty"`
+	Tag            string     `json:"tag,omitempty"`
+	CreatedAt      *time.Time `json:"created_at"`
+	StoppedAt      *time.Time `json:"stopped_at"`
+}
+
+// Finished reports whether the workflow has reached a terminal status, and
+// will not change status again.
+func (workflow Workflow) Finished() bool {
+	switch workflow.Status {
+	case "success", "failed", "error", "canceled", "unauthorized", "not_run":
+		return true
+	default:
+		return false
+	}
+}
+
+// Job is a single v2 job, belonging to a workflow.
+//
+// See https://circleci.com/docs/api/v2/#operation/listWorkflowJobs for
+// details on the fields of a job.
+type Job struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Type              string     `json:"type"`
+	Status            string     `json:"status"`
+	JobNumber         int        `json:"job_number,omitempty"`
+	ProjectSlug       string     `json:"project_slug"`
+	ApprovalRequestID string     `json:"approval_request_id,omitempty"`
+	Dependencies      []string   `json:"dependencies"`
+	StartedAt         *time.Time `json:"started_at"`
+	StoppedAt         *time.Time `json:"stopped_at"`
+}
+
+// JobURL returns the web URL for the given job, or an empty string if the job
+// has not been run, such as an approval job.
+func (client Client) JobURL(job Job) string {
+	if job.JobNumber == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("https://%s/%s/%d", client.host, job.ProjectSlug, job.JobNumber)
+}
+
+// GetPipelineWorkflows fetches every workflow belonging to the pipeline with
+// the given ID.
+//
+// See https://circleci.com/docs/api/v2/#operation/listWorkflowsByPipelineId
+// for details on this API action.
+func (client Client) GetPipelineWorkflows(id string) ([]Workflow, error) {
+	return client.GetPipelineWorkflowsContext(context.Background(), id)
+}
+
+// GetPipelineWorkflowsContext is like GetPipelineWorkflows, but uses the
+// given context for the requests.
+func (client Client) GetPipelineWorkflowsContext(ctx context.Context, id string) ([]Workflow, error) {
+	// https://circleci.com/api/v2/pipeline/:pipeline-id/workflow
+	path := fmt.Sprintf("pipeline/%s/workflow", url.PathEscape(id))
+
+	var workflows []Workflow
+	err := client.paginate(ctx, path, nil, func(items json.RawMessage) (bool, error) {
+		var page []Workflow
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+		workflows = append(workflows, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return workflows, nil
+}
+
+// GetWorkflow fetches the workflow with the given ID.
+//
+// See https://circleci.com/docs/api/v2/#operation/getWorkflowById for details
+// on this API action.
+func (client Client) GetWorkflow(id string) (*Workflow, error) {
+	return client.GetWorkflowContext(context.Background(), id)
+}
+
+// GetWorkflowContext is like GetWorkflow, but uses the given context for the
+// request.
+func (client Client) GetWorkflowContext(ctx context.Context, id string) (*Workflow, error) {
+	// https://circleci.com/api/v2/workflow/:id
+	path := fmt.Sprintf("workflow/%s", url.PathEscape(id))
+
+	var result Workflow
+	if err := client.request(ctx, "GET", client.v2(path), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetWorkflowJobs fetches every job belonging to the workflow with the given
+// ID.
+//
+// See https://circleci.com/docs/api/v2/#operation/listWorkflowJobs for
+// details on this API action.
+func (client Client) GetWorkflowJobs(id string) ([]Job, error) {
+	return client.GetWorkflowJobsContext(context.Background(), id)
+}
+
+// GetWorkflowJobsContext is like GetWorkflowJobs, but uses the given context
+// for the requests.
+func (client Client) GetWorkflowJobsContext(ctx context.Context, id string) ([]Job, error) {
+	// https://circleci.com/api/v2/workflow/:id/job
+	path := fmt.Sprintf("workflow/%s/job", url.PathEscape(id))
+
+	var jobs []Job
+	err := client.paginate(ctx, path, nil, func(items json.RawMessage) (bool, error) {
+		var page []Job
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+		jobs = append(jobs, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// CancelWorkflow cancels the workflow with the given ID.
+//
+// See https://circleci.com/docs/api/v2/#operation/cancelWorkflow for details
+// on this API action.
+func (client Client) CancelWorkflow(id string) error {
+	return client.CancelWorkflowContext(context.Background(), id)
+}
+
+// CancelWorkflowContext is like CancelWorkflow, but uses the given context for
+// the request.
+func (client Client) CancelWorkflowContext(ctx context.Context, id string) error {
+	// https://circleci.com/api/v2/workflow/:id/cancel
+	path := fmt.Sprintf("workflow/%s/cancel", url.PathEscape(id))
+
+	var result struct {
+		Message string `json:"message"`
+	}
+
+	return client.request(ctx, "POST", client.v2(path), nil, &result)
+}
+
+// ApproveJob approves the pending approval job with the given approval
+// request ID, allowing the rest of the workflow to continue.
+//
+// See https://circleci.com/docs/api/v2/#operation/approvePendingApprovalJobById
+// for details on this API action.
+func (client Client) ApproveJob(workflowID string, approvalRequestID string) error {
+	return client.ApproveJobContext(context.Background(), workflowID, approvalRequestID)
+}
+
+// ApproveJobContext is like ApproveJob, but uses the given context for the
+// request.
+func (client Client) ApproveJobContext(ctx context.Context, workflowID string, approvalRequestID string) error {
+	// https://circleci.com/api/v2/workflow/:id/approve/:approval_request_id
+	path := fmt.Sprintf("workflow/%s/approve/%s", url.PathEscape(workflowID), url.PathEscape(approvalRequestID))
+
+	var result struct {
+		Message string `json:"message"`
+	}
+
+	return client.request(ctx, "POST", client.v2(path), nil, &result)
+}
+
+// RerunWorkflowOptions configures how a workflow is rerun. If neither
+// FromFailed nor Jobs are given, the entire workflow is rerun.
+type RerunWorkflowOptions struct {
+	// EnableSSH enables SSH access to the rerun jobs.
+	EnableSSH bool `json:"enable_ssh,omitempty"`
+
+	// FromFailed reruns only the failed jobs, and the jobs that depend on
+	// them.
+	FromFailed bool `json:"from_failed,omitempty"`
+
+	// Jobs are the IDs of the jobs to rerun.
+	Jobs []string `json:"jobs,omitempty"`
+
+	// SparseTree reruns only the given jobs, and not the jobs that depend on
+	// them.
+	SparseTree bool `json:"sparse_tree,omitempty"`
+}
+
+// RerunWorkflow reruns the workflow with the given ID, and returns the ID of
+// the new workflow.
+//
+// See https://circleci.com/docs/api/v2/#operation/rerunWorkflow for details
+// on this API action.
+func (client Client) RerunWorkflow(id string, options RerunWorkflowOptions) (string, error) {
+	return client.RerunWorkflowContext(context.Background(), id, options)
+}
+
+// RerunWorkflowContext is like RerunWorkflow, but uses the given context for
+// the request.
+func (client Client) RerunWorkflowContext(ctx context.Context, id string, options RerunWorkflowOptions) (string, error) {
+	if options.FromFailed && len(options.Jobs) != 0 {
+		return "", errors.New("only one of from failed or jobs may be given")
+	}
+
+	if options.SparseTree && len(options.Jobs) == 0 {
+		return "", errors.New("sparse tree requires jobs to be given")
+	}
+
+	// https://circleci.com/api/v2/workflow/:id/rerun
+	path := fmt.Sprintf("workflow/%s/rerun", url.PathEscape(id))
+
+	var result struct {
+		WorkflowID string `json:"workflow_id"`
+	}
+	if err := client.request(ctx, "POST", client.v2(path), options, &result); err != nil {
+		return "", err
+	}
+
+	return result.WorkflowID, nil
+}
+
+// paginate fetches every page of the given v2 list endpoint, passing the
+// items of each page to collect. Fetching stops early if collect returns
+// false.
+func (client Client) paginate(ctx context.Context, path string, query url.Values, collect func(items json.RawMessage) (bool, error)) error {
+	var pageToken string
+
+	for {
+		params := url.Values{}
+		for key, values := range query {
+			params[key] = values
+		}
+		if pageToken != "" {
+			params.Set("page-token", pageToken)
+		}
+
+		endpoint := client.v2(path)
+		if len(params) > 0 {
+			endpoint += "?" + params.Encode()
+		}
+
+		var page struct {
+			Items         json.RawMessage `json:"items"`
+			NextPageToken string          `json:"next_page_token"`
+		}
+		if err := client.request(ctx, "GET", endpoint, nil, &page); err != nil {
+			return err
+		}
+
+		more, err := collect(page.Items)
+		if err != nil {
+			return err
+		}
+
+		if !more || page.NextPageToken == "" {
+			return nil
+		}
+
+		pageToken = page.NextPageToken
+	}
+}
@@ -5,6 +5,7 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -13,7 +14,7 @@ import (
 
 type action uint
 
-type handler func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error)
+type handler func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error)
 
 const (
 	unknown action = iota
@@ -95,38 +96,38 @@ func getHandler(action action, build string, ssh bool, tag string, branch string
 	switch action {
 	case buildDefault:
 		return "build default branch",
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.BuildDefault(vcs, username, project, params)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.BuildDefaultContext(ctx, vcs, username, project, params)
 			}
 	case buildBranch:
 		return fmt.Sprintf("build branch %s", branch),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.BuildBranch(vcs, username, project, branch, params)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.BuildBranchContext(ctx, vcs, username, project, branch, params)
 			}
 	case buildBranchAtRef:
 		return fmt.Sprintf("build branch %s at %s", branch, ref),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.BuildBranchAtRef(vcs, username, project, branch, ref, params)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.BuildBranchAtRefContext(ctx, vcs, username, project, branch, ref, params)
 			}
 	case buildRef:
 		return fmt.Sprintf("build ref %s", ref),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.BuildRef(vcs, username, project, ref, params)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.BuildRefContext(ctx, vcs, username, project, ref, params)
 			}
 	case buildTag:
 		return fmt.Sprintf("build tag %s", tag),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.BuildTag(vcs, username, project, tag, params)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.BuildTagContext(ctx, vcs, username, project, tag, params)
 			}
 	case rebuild:
 		return fmt.Sprintf("rebuild #%s", build),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.Rebuild(vcs, username, project, build)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.RebuildContext(ctx, vcs, username, project, build)
 			}
 	case rebuildWithSSH:
 		return fmt.Sprintf("rebuild #%s with SSH", build),
-			func(client cci.Client, vcs string, username string, project string) (*cci.BuildResponse, error) {
-				return client.RebuildWithSSH(vcs, username, project, build)
+			func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+				return client.RebuildWithSSHContext(ctx, vcs, username, project, build)
 			}
 	default:
 		return "invalid flag combination", nil
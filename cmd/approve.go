@@ -0,0 +1,183 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	jobNameParam = flag.StringParam{
+		Name: "job",
+	}
+	pipelineIDFlag = flag.StringFlag{
+		Name:  "pipeline",
+		Usage: "ID of a v2 pipeline",
+	}
+	allFlag = flag.BoolFlag{
+		Name:  "all",
+		Usage: "act on every match, instead of refusing when there are several",
+	}
+)
+
+// approval is a pending approval job, and the workflow it belongs to.
+type approval struct {
+	WorkflowID        string `json:"workflow_id"`
+	WorkflowName      string `json:"workflow_name"`
+	JobName           string `json:"job_name"`
+	ApprovalRequestID string `json:"approval_request_id"`
+}
+
+func approveCommand() cli.Command {
+	return cli.Command{
+		Name:  "approve",
+		Usage: "Approve an on hold approval job by name",
+		Flags: append([]flag.Flag{
+			jobNameParam,
+			pipelineIDFlag,
+			workflowFlag,
+			allFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				name     = ctx.String(jobNameParam.Name)
+				pipeline = ctx.String(pipelineIDFlag.Name)
+				workflow = ctx.String(workflowFlag.Name)
+				all      = ctx.Bool(allFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			if (pipeline == "") == (workflow == "") {
+				return errors.New("invalid flag combination")
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			approvals, err := approveJobs(ctx.Context(), client, pipeline, workflow, name, all)
+			if err != nil {
+				// Jobs that were already approved are still listed, so that
+				// it is clear which ones are left
+				if len(approvals) != 0 {
+					_ = printApprovals(ctx, out, approvals)
+				}
+				return err
+			}
+
+			return printApprovals(ctx, out, approvals)
+		},
+	}
+}
+
+// printApprovals prints the given approved jobs.
+func printApprovals(ctx cli.Context, out printer, approvals []approval) error {
+	return out.print(ctx.App.Stdout, approvals, func(w io.Writer) error {
+		for _, approval := range approvals {
+			if _, err := fmt.Fprintf(w, "approved %s in workflow %s (%s)\n", approval.JobName, approval.WorkflowName, approval.WorkflowID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// approveJobs approves the on hold approval job with the given name, in
+// either every workflow of the given pipeline, or in the given workflow. If
+// there are several matching jobs, they are only approved if all is true. If
+// a job fails to be approved, the jobs that were approved before it are
+// returned along with the error.
+func approveJobs(ctx context.Context, client cci.Client, pipeline string, workflow string, name string, all bool) ([]approval, error) {
+	approvals, err := findApprovals(ctx, client, pipeline, workflow, name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(approvals) == 0:
+		return nil, fmt.Errorf("no on hold approval job named %q", name)
+
+	case len(approvals) > 1 && !all:
+		workflows := make([]string, len(approvals))
+		for index, approval := range approvals {
+			workflows[index] = fmt.Sprintf("%s (%s)", approval.WorkflowName, approval.WorkflowID)
+		}
+		return nil, fmt.Errorf("multiple on hold approval jobs named %q, in workflows %s", name, strings.Join(workflows, ", "))
+	}
+
+	approved := make([]approval, 0, len(approvals))
+
+	for _, approval := range approvals {
+		if err := client.ApproveJobContext(ctx, approval.WorkflowID, approval.ApprovalRequestID); err != nil {
+			return approved, err
+		}
+		approved = append(approved, approval)
+	}
+
+	return approved, nil
+}
+
+// findApprovals searches for on hold approval jobs with the given name, in
+// either every workflow of the given pipeline, or in the given workflow.
+func findApprovals(ctx context.Context, client cci.Client, pipeline string, workflow string, name string) ([]approval, error) {
+	var workflows []cci.Workflow
+
+	if pipeline != "" {
+		var err error
+		if workflows, err = client.GetPipelineWorkflowsContext(ctx, pipeline); err != nil {
+			return nil, err
+		}
+	} else {
+		resp, err := client.GetWorkflowContext(ctx, workflow)
+		if err != nil {
+			return nil, err
+		}
+		workflows = []cci.Workflow{*resp}
+	}
+
+	var approvals []approval
+
+	for _, workflow := range workflows {
+		jobs, err := client.GetWorkflowJobsContext(ctx, workflow.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, job := range jobs {
+			if job.Type != "approval" || job.Status != "on_hold" || job.Name != name {
+				continue
+			}
+
+			id := job.ApprovalRequestID
+			if id == "" {
+				id = job.ID
+			}
+
+			approvals = append(approvals, approval{
+				WorkflowID:        workflow.ID,
+				WorkflowName:      workflow.Name,
+				JobName:           job.Name,
+				ApprovalRequestID: id,
+			})
+		}
+	}
+
+	return approvals, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestApproveJobs(t *testing.T) {
+
+	tests := []struct {
+		title     string
+		pipeline  string
+		workflow  string
+		name      string
+		all       bool
+		approved  []string
+		approvals []approval
+		fail      string
+		err       string
+		notFound  bool
+	}{
+		{
+			title:    "on hold approval in workflow",
+			workflow: "w1",
+			name:     "hold",
+			approved: []string{"w1/r3"},
+			approvals: []approval{
+				{WorkflowID: "w1", WorkflowName: "build", JobName: "hold", ApprovalRequestID: "r3"},
+			},
+		},
+		{
+			title:    "approval without approval request id",
+			workflow: "w2",
+			name:     "hold",
+			approved: []string{"w2/j5"},
+			approvals: []approval{
+				{WorkflowID: "w2", WorkflowName: "deploy", JobName: "hold", ApprovalRequestID: "j5"},
+			},
+		},
+		{
+			title:    "multiple approvals in pipeline",
+			pipeline: "p1",
+			name:     "hold",
+			err:      `multiple on hold approval jobs named "hold", in workflows build (w1), deploy (w2)`,
+		},
+		{
+			title:    "multiple approvals in pipeline with all",
+			pipeline: "p1",
+			name:     "hold",
+			all:      true,
+			approved: []string{"w1/r3", "w2/j5"},
+			approvals: []approval{
+				{WorkflowID: "w1", WorkflowName: "build", JobName: "hold", ApprovalRequestID: "r3"},
+				{WorkflowID: "w2", WorkflowName: "deploy", JobName: "hold", ApprovalRequestID: "j5"},
+			},
+		},
+		{
+			title:    "failed approval with all",
+			pipeline: "p1",
+			name:     "hold",
+			all:      true,
+			fail:     "w2/j5",
+			approved: []string{"w1/r3"},
+			approvals: []approval{
+				{WorkflowID: "w1", WorkflowName: "build", JobName: "hold", ApprovalRequestID: "r3"},
+			},
+			notFound: true,
+		},
+		{
+			title:    "other approval name",
+			workflow: "w1",
+			name:     "other",
+			approved: []string{"w1/r4"},
+			approvals: []approval{
+				{WorkflowID: "w1", WorkflowName: "build", JobName: "other", ApprovalRequestID: "r4"},
+			},
+		},
+		{
+			title:    "no matching approval",
+			pipeline: "p1",
+			name:     "missing",
+			err:      `no on hold approval job named "missing"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var approved []string
+
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch path := strings.TrimPrefix(r.URL.Path, "/api/v2/"); path {
+				case "pipeline/p1/workflow":
+					fmt.Fprint(w, `{"items": [{"id": "w1", "name": "build"}, {"id": "w2", "name": "deploy"}]}`)
+				case "workflow/w1":
+					fmt.Fprint(w, `{"id": "w1", "name": "build"}`)
+				case "workflow/w2":
+					fmt.Fprint(w, `{"id": "w2", "name": "deploy"}`)
+				case "workflow/w1/job":
+					// Only the on hold approval jobs named hold or other match
+					fmt.Fprint(w, `{"items": [
+						{"id": "j1", "name": "hold", "type": "build", "status": "on_hold"},
+						{"id": "j2", "name": "hold", "type": "approval", "status": "success", "approval_request_id": "r2"},
+						{"id": "j3", "name": "hold", "type": "approval", "status": "on_hold", "approval_request_id": "r3"},
+						{"id": "j4", "name": "other", "type": "approval", "status": "on_hold", "approval_request_id": "r4"}
+					]}`)
+				case "workflow/w2/job":
+					fmt.Fprint(w, `{"items": [{"id": "j5", "name": "hold", "type": "approval", "status": "on_hold"}]}`)
+				default:
+					require.Equal(t, "POST", r.Method)
+
+					id := strings.TrimPrefix(strings.Replace(path, "/approve/", "/", 1), "workflow/")
+					if id == test.fail {
+						w.WriteHeader(http.StatusNotFound)
+						fmt.Fprint(w, `{"message": "Not found."}`)
+						return
+					}
+
+					approved = append(approved, id)
+					fmt.Fprint(w, `{"message": "Accepted."}`)
+				}
+			})
+			defer done()
+
+			approvals, err := approveJobs(context.Background(), client, test.pipeline, test.workflow, test.name, test.all)
+			switch {
+			case test.notFound:
+				require.True(t, cci.IsNotFound(err))
+			case test.err != "":
+				require.EqualError(t, err, test.err)
+			default:
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, test.approvals, approvals)
+			require.Equal(t, test.approved, approved)
+		})
+	}
+}
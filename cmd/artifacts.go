@@ -0,0 +1,310 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	jobNumberFlag = flag.StringFlag{
+		Name:  "job-number",
+		Usage: "number of a v2 job",
+	}
+	downloadFlag = flag.StringFlag{
+		Name:  "download",
+		Usage: "directory to download artifacts into, resuming any partial files",
+	}
+	matchFlag = flag.StringFlag{
+		Name:  "match",
+		Usage: "only include artifacts matching the given glob, which is matched against the file name unless it contains a slash",
+	}
+	parallelFlag = flag.IntFlag{
+		Name:  "parallel",
+		Value: 4,
+		Usage: "number of artifacts to download at once",
+	}
+)
+
+// artifactResult is an artifact, and where it was downloaded to if it was.
+type artifactResult struct {
+	cci.Artifact
+	File   string `json:"file,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+func artifactsCommand() cli.Command {
+	return cli.Command{
+		Name:  "artifacts",
+		Usage: "List or download the artifacts of a build or job",
+		Flags: append([]flag.Flag{
+			optionalProjectParam,
+			buildFlag,
+			jobNumberFlag,
+			matchFlag,
+			downloadFlag,
+			parallelFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project  = ctx.String(optionalProjectParam.Name)
+				build    = ctx.String(buildFlag.Name)
+				job      = ctx.String(jobNumberFlag.Name)
+				pattern  = ctx.String(matchFlag.Name)
+				dir      = ctx.String(downloadFlag.Name)
+				parallel = ctx.Int(parallelFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			if parallel < 1 {
+				return fmt.Errorf("invalid parallelism %d", parallel)
+			}
+
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid match pattern %q", pattern)
+			}
+
+			projectVCS, projectUsername, projectName, projectBuild, err := resolveProjectBuild(ctx, project)
+			if err != nil {
+				return err
+			}
+
+			// A build URL implies the build flag, unless a job is given
+			if projectBuild != "" && build == "" && job == "" {
+				build = projectBuild
+			}
+
+			if (build == "") == (job == "") {
+				return errors.New("invalid flag combination")
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			var artifacts []cci.Artifact
+			if build != "" {
+				artifacts, err = client.ListBuildArtifactsContext(ctx.Context(), projectVCS, projectUsername, projectName, build)
+			} else {
+				artifacts, err = client.ListJobArtifactsContext(ctx.Context(), projectVCS, projectUsername, projectName, job)
+			}
+			if err != nil {
+				return err
+			}
+
+			results := make([]artifactResult, 0, len(artifacts))
+			for _, artifact := range artifacts {
+				if matchArtifact(pattern, artifact.Path) {
+					results = append(results, artifactResult{Artifact: artifact})
+				}
+			}
+
+			if dir != "" {
+				if err := downloadArtifacts(ctx.Context(), client, dir, results, parallel); err != nil {
+					return err
+				}
+			}
+
+			return out.print(ctx.App.Stdout, results, func(w io.Writer) error {
+				for _, result := range results {
+					// Downloads are listed in the same format as sha256sum
+					line := fmt.Sprintf("%s  %s", result.SHA256, result.File)
+					if dir == "" {
+						line = result.URL
+					}
+
+					if _, err := fmt.Fprintln(w, line); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// matchArtifact reports whether the given artifact path matches the given
+// glob pattern. Patterns without a slash are matched against the file name
+// alone, and an empty pattern matches everything.
+func matchArtifact(pattern string, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// artifactFile returns the file that the given artifact should be downloaded
+// to, inside the given directory. Artifacts from parallel nodes other than
+// the first are placed in a separate directory for each node.
+func artifactFile(dir string, artifact cci.Artifact) string {
+	// Cleaning the path as if it were absolute removes any leading ".."
+	// elements, so that the file can not escape the directory
+	name := path.Clean("/" + artifact.Path)
+
+	if artifact.NodeIndex != 0 {
+		name = path.Join(fmt.Sprintf("node%d", artifact.NodeIndex), name)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(name))
+}
+
+// downloadArtifacts downloads each of the given artifacts into the given
+// directory, with at most parallel downloads running at once. The file, size
+// and checksum of each result are filled in.
+func downloadArtifacts(ctx context.Context, client cci.Client, dir string, results []artifactResult, parallel int) error {
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		errs   []error
+		tokens = make(chan struct{}, parallel)
+	)
+
+	for index := range results {
+		wg.Add(1)
+		tokens <- struct{}{}
+
+		go func(result *artifactResult) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+
+			result.File = artifactFile(dir, result.Artifact)
+
+			size, sum, err := downloadArtifact(ctx, client, result.Artifact, result.File)
+			if err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("downloading %s: %s", result.Path, err.Error()))
+				mutex.Unlock()
+				return
+			}
+
+			result.Size = size
+			result.SHA256 = sum
+		}(&results[index])
+	}
+
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+// downloadArtifact downloads the given artifact to the given file. The
+// artifact is first written to the file with a .part suffix, which is renamed
+// once the download is complete, so that a download can be resumed from where
+// it stopped, while an existing complete file is never appended to. The size
+// and SHA-256 checksum of the complete file are returned.
+func downloadArtifact(ctx context.Context, client cci.Client, artifact cci.Artifact, file string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return 0, "", err
+	}
+
+	part := file + ".part"
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	written, err := downloadPart(ctx, client, artifact, part, offset)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Nothing could be resumed from the partial file, which is either already
+	// complete or was left by a different artifact, so it cannot be trusted
+	// and the download is started over
+	if offset > 0 && written == 0 {
+		if _, err := downloadPart(ctx, client, artifact, part, 0); err != nil {
+			return 0, "", err
+		}
+	}
+
+	if err := os.Rename(part, file); err != nil {
+		return 0, "", err
+	}
+
+	return checksum(file)
+}
+
+// downloadPart writes the given artifact to the given partial file, starting
+// from the given offset, and returns the number of bytes written.
+func downloadPart(ctx context.Context, client cci.Client, artifact cci.Artifact, part string, offset int64) (int64, error) {
+	body, start, err := client.OpenArtifactContext(ctx, artifact, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = body.Close() }()
+
+	// Append to the partial file if the download was resumed, otherwise start
+	// over from the beginning
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if start > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+
+	dest, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(dest, body)
+	if err != nil {
+		_ = dest.Close()
+		return 0, err
+	}
+
+	return written, dest.Close()
+}
+
+// checksum returns the size and SHA-256 checksum of the given file.
+func checksum(file string) (int64, string, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = src.Close() }()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, src)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
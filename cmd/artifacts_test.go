@@ -0,0 +1,179 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestMatchArtifact(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		pattern string
+		path    string
+		matched bool
+	}{
+		{
+			title:   "empty pattern",
+			path:    "dist/example.tar.gz",
+			matched: true,
+		},
+		{
+			title:   "file name pattern",
+			pattern: "*.tar.gz",
+			path:    "dist/linux/example.tar.gz",
+			matched: true,
+		},
+		{
+			title:   "file name pattern mismatch",
+			pattern: "*.tar.gz",
+			path:    "dist/linux/example.zip",
+		},
+		{
+			title:   "path pattern",
+			pattern: "dist/*/example.tar.gz",
+			path:    "dist/linux/example.tar.gz",
+			matched: true,
+		},
+		{
+			title:   "path pattern mismatch",
+			pattern: "dist/*.tar.gz",
+			path:    "dist/linux/example.tar.gz",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.matched, matchArtifact(test.pattern, test.path))
+		})
+	}
+}
+
+func TestArtifactFile(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		artifact cci.Artifact
+		file     string
+	}{
+		{
+			title:    "relative path",
+			artifact: cci.Artifact{Path: "dist/example.tar.gz"},
+			file:     "out/dist/example.tar.gz",
+		},
+		{
+			title:    "absolute path",
+			artifact: cci.Artifact{Path: "/home/circleci/dist/example.tar.gz"},
+			file:     "out/home/circleci/dist/example.tar.gz",
+		},
+		{
+			title:    "escaping path",
+			artifact: cci.Artifact{Path: "../../etc/passwd"},
+			file:     "out/etc/passwd",
+		},
+		{
+			title:    "parallel node",
+			artifact: cci.Artifact{Path: "dist/example.tar.gz", NodeIndex: 2},
+			file:     "out/node2/dist/example.tar.gz",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, filepath.FromSlash(test.file), artifactFile("out", test.artifact))
+		})
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	const contents = "artifact contents"
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "example.txt", time.Time{}, strings.NewReader(contents))
+	}))
+	defer server.Close()
+
+	client := cci.NewWithHost("token", strings.TrimPrefix(server.URL, "https://")).WithHTTPClient(server.Client())
+	artifact := cci.Artifact{Path: "example.txt", URL: server.URL + "/example.txt"}
+
+	sum := sha256.Sum256([]byte(contents))
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		title string
+		file  string
+		part  string
+	}{
+		{
+			title: "new download",
+		},
+		{
+			title: "stale complete file",
+			file:  "old artifact contents",
+		},
+		{
+			title: "resumed partial file",
+			part:  "artifact ",
+		},
+		{
+			title: "complete partial file",
+			part:  contents,
+		},
+		{
+			title: "oversized partial file",
+			part:  "a different, larger artifact",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cci-trigger")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "example.txt")
+			if test.file != "" {
+				require.NoError(t, ioutil.WriteFile(file, []byte(test.file), 0644))
+			}
+			if test.part != "" {
+				require.NoError(t, ioutil.WriteFile(file+".part", []byte(test.part), 0644))
+			}
+
+			size, actual, err := downloadArtifact(context.Background(), client, artifact, file)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(contents)), size)
+			require.Equal(t, digest, actual)
+
+			body, err := ioutil.ReadFile(file)
+			require.NoError(t, err)
+			require.Equal(t, contents, string(body))
+
+			_, err = os.Stat(file + ".part")
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	manifestParam = optionalParam{
+		Name:  "manifest",
+		Usage: "path to a YAML or JSON manifest of builds to trigger, read from stdin if omitted",
+	}
+	concurrencyFlag = flag.IntFlag{
+		Name:  "concurrency",
+		Value: 4,
+		Usage: "number of builds to trigger at once",
+	}
+)
+
+// manifest is a list of builds to trigger, as read by the batch command.
+type manifest struct {
+	// Params are build parameters given to every target, which can be
+	// overridden by the params of each target.
+	Params map[string]string `yaml:"params"`
+
+	// Targets are the builds to trigger.
+	Targets []manifestTarget `yaml:"targets"`
+}
+
+// manifestTarget is a single build to trigger. The fields match the flags of
+// the root command.
+type manifestTarget struct {
+	Project string            `yaml:"project"`
+	Branch  string            `yaml:"branch"`
+	Tag     string            `yaml:"tag"`
+	Ref     string            `yaml:"ref"`
+	Params  map[string]string `yaml:"params"`
+}
+
+// batchTarget is a manifest target that has been resolved into the project
+// and handler used to trigger it.
+type batchTarget struct {
+	vcs         string
+	username    string
+	project     string
+	description string
+	handler     handler
+}
+
+// batchResult is the outcome of triggering a single manifest target.
+type batchResult struct {
+	Project  string `json:"project"`
+	Action   string `json:"action"`
+	BuildNum int    `json:"build_num,omitempty"`
+	BuildURL string `json:"build_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func batchCommand() cli.Command {
+	return cli.Command{
+		Name:  "batch",
+		Usage: "Trigger every build listed in a manifest file",
+		Flags: append([]flag.Flag{
+			manifestParam,
+			concurrencyFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				path        = ctx.String(manifestParam.Name)
+				concurrency = ctx.Int(concurrencyFlag.Name)
+				output      = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			if concurrency < 1 {
+				return fmt.Errorf("invalid concurrency %d", concurrency)
+			}
+
+			// Both of these would read stdin, and only one of them can
+			if path == "" {
+				stdin, err := tokenFromStdin(ctx)
+				if err != nil {
+					return err
+				}
+				if stdin {
+					return errors.New("the API token and the manifest cannot both be read from stdin")
+				}
+			}
+
+			list, err := readManifest(path)
+			if err != nil {
+				return err
+			}
+
+			// Every target is resolved up front, so that a mistake in the
+			// manifest does not leave it partially triggered
+			targets := make([]batchTarget, 0, len(list.Targets))
+			for index, target := range list.Targets {
+				resolved, err := resolveTarget(ctx, list.Params, target)
+				if err != nil {
+					return fmt.Errorf("invalid manifest target #%d: %s", index+1, err.Error())
+				}
+				targets = append(targets, resolved)
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			results := triggerTargets(ctx.Context(), client, targets, concurrency)
+
+			return printBatch(ctx, out, results)
+		},
+	}
+}
+
+// printBatch prints the given results, and returns an error if any of the
+// builds failed to trigger.
+func printBatch(ctx cli.Context, out printer, results []batchResult) error {
+	err := out.print(ctx.App.Stdout, results, func(w io.Writer) error {
+		return renderBatch(w, results)
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d builds failed to trigger", failed, len(results))
+	}
+
+	return nil
+}
+
+// readManifest reads the manifest at the given path, or from stdin if the
+// path is empty. As JSON is a subset of YAML, both formats are supported.
+func readManifest(path string) (*manifest, error) {
+	var (
+		body []byte
+		err  error
+	)
+
+	if path == "" {
+		body, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		body, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %s", err.Error())
+	}
+
+	var list manifest
+	if err := yaml.UnmarshalStrict(body, &list); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %s", err.Error())
+	}
+
+	if len(list.Targets) == 0 {
+		return nil, errors.New("invalid manifest: no targets")
+	}
+
+	// Params are checked the same way as those given on the command line
+	for key := range list.Params {
+		if !regexBuildVar.MatchString(key) {
+			return nil, fmt.Errorf("invalid manifest: invalid build parameter %q", key)
+		}
+	}
+	for index, target := range list.Targets {
+		for key := range target.Params {
+			if !regexBuildVar.MatchString(key) {
+				return nil, fmt.Errorf("invalid manifest target #%d: invalid build parameter %q", index+1, key)
+			}
+		}
+	}
+
+	return &list, nil
+}
+
+// resolveTarget resolves the project and action of the given target, with
+// the given default params merged into its own.
+func resolveTarget(ctx cli.Context, defaults map[string]string, target manifestTarget) (batchTarget, error) {
+	if target.Project == "" {
+		return batchTarget{}, errors.New("no project given")
+	}
+
+	vcs, username, project, err := resolveProject(ctx, target.Project)
+	if err != nil {
+		return batchTarget{}, err
+	}
+
+	var params map[string]string
+	if len(defaults)+len(target.Params) != 0 {
+		params = make(map[string]string, len(defaults)+len(target.Params))
+		for key, value := range defaults {
+			params[key] = value
+		}
+		for key, value := range target.Params {
+			params[key] = value
+		}
+	}
+
+	action, err := getAction("", false, target.Tag, target.Branch, target.Ref, params)
+	if err != nil {
+		return batchTarget{}, err
+	}
+
+	description, handler := getHandler(action, "", false, target.Tag, target.Branch, target.Ref, params)
+	if handler == nil {
+		return batchTarget{}, errors.New(description)
+	}
+
+	return batchTarget{
+		vcs:         vcs,
+		username:    username,
+		project:     project,
+		description: description,
+		handler:     handler,
+	}, nil
+}
+
+// triggerTargets triggers each of the given targets, with at most
+// concurrency builds being triggered at once. A result is returned for every
+// target, in the same order.
+func triggerTargets(ctx context.Context, client cci.Client, targets []batchTarget, concurrency int) []batchResult {
+	var (
+		wg      sync.WaitGroup
+		tokens  = make(chan struct{}, concurrency)
+		results = make([]batchResult, len(targets))
+	)
+
+	for index, target := range targets {
+		wg.Add(1)
+		tokens <- struct{}{}
+
+		go func(result *batchResult, target batchTarget) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+
+			result.Project = cci.ProjectSlug(target.vcs, target.username, target.project)
+			result.Action = target.description
+
+			resp, err := target.handler(ctx, client, target.vcs, target.username, target.project)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+
+			result.BuildNum = resp.BuildNum
+			result.BuildURL = resp.BuildURL
+		}(&results[index], target)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// renderBatch writes the given results as a table.
+func renderBatch(w io.Writer, results []batchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PROJECT\tACTION\tRESULT")
+
+	for _, result := range results {
+		outcome := result.BuildURL
+		if result.Error != "" {
+			outcome = "error: " + result.Error
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Project, result.Action, outcome)
+	}
+
+	return tw.Flush()
+}
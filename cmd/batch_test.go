@@ -0,0 +1,154 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		title    string
+		body     string
+		manifest *manifest
+		err      string
+	}{
+		{
+			title: "yaml",
+			body: `
+params:
+  VERSION: 1.2.3
+targets:
+  - project: alice/api
+    branch: master
+  - project: alice/web
+    tag: v1.0.0
+    params:
+      DEPLOY: true
+`,
+			manifest: &manifest{
+				Params: map[string]string{"VERSION": "1.2.3"},
+				Targets: []manifestTarget{
+					{Project: "alice/api", Branch: "master"},
+					{Project: "alice/web", Tag: "v1.0.0", Params: map[string]string{"DEPLOY": "true"}},
+				},
+			},
+		},
+		{
+			title: "json",
+			body:  `{"targets": [{"project": "alice/api", "ref": "2c9bd6df"}]}`,
+			manifest: &manifest{
+				Targets: []manifestTarget{
+					{Project: "alice/api", Ref: "2c9bd6df"},
+				},
+			},
+		},
+		{
+			title: "no targets",
+			body:  `params: {VERSION: 1.2.3}`,
+			err:   "invalid manifest: no targets",
+		},
+		{
+			title: "invalid param",
+			body:  `{"params": {"MY-VAR": "1"}, "targets": [{"project": "alice/api"}]}`,
+			err:   `invalid manifest: invalid build parameter "MY-VAR"`,
+		},
+		{
+			title: "invalid target param",
+			body:  `{"targets": [{"project": "alice/api"}, {"project": "alice/web", "params": {"1VAR": "1"}}]}`,
+			err:   `invalid manifest target #2: invalid build parameter "1VAR"`,
+		},
+		{
+			title: "unknown field",
+			body:  `{"targets": [{"project": "alice/api", "brnach": "master"}]}`,
+			err:   "invalid manifest: yaml: unmarshal errors:\n  line 1: field brnach not found in type cmd.manifestTarget",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("manifest-%d.yml", index))
+			require.NoError(t, ioutil.WriteFile(path, []byte(test.body), 0644))
+
+			actual, err := readManifest(path)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.manifest, actual)
+		})
+	}
+}
+
+func TestTriggerTargets(t *testing.T) {
+	var running, peak int32
+
+	handler := func(ctx context.Context, client cci.Client, vcs string, username string, project string) (*cci.Build, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if project == "broken" {
+			return nil, errors.New("project not found")
+		}
+		return &cci.Build{BuildNum: 1, BuildURL: "https://circleci.com/gh/alice/" + project + "/1"}, nil
+	}
+
+	var targets []batchTarget
+	for _, project := range []string{"api", "web", "broken", "worker", "docs"} {
+		targets = append(targets, batchTarget{"github", "alice", project, "build default branch", handler})
+	}
+
+	results := triggerTargets(context.Background(), cci.New("token"), targets, 2)
+
+	require.Len(t, results, 5)
+	require.True(t, peak <= 2)
+	require.Equal(t, "gh/alice/api", results[0].Project)
+	require.Equal(t, "https://circleci.com/gh/alice/api/1", results[0].BuildURL)
+	require.Equal(t, "gh/alice/broken", results[2].Project)
+	require.Equal(t, "project not found", results[2].Error)
+	require.Equal(t, "https://circleci.com/gh/alice/docs/1", results[4].BuildURL)
+
+	expected := "" +
+		"PROJECT          ACTION                RESULT\n" +
+		"gh/alice/api     build default branch  https://circleci.com/gh/alice/api/1\n" +
+		"gh/alice/web     build default branch  https://circleci.com/gh/alice/web/1\n" +
+		"gh/alice/broken  build default branch  error: project not found\n" +
+		"gh/alice/worker  build default branch  https://circleci.com/gh/alice/worker/1\n" +
+		"gh/alice/docs    build default branch  https://circleci.com/gh/alice/docs/1\n"
+
+	var buf bytes.Buffer
+	require.NoError(t, renderBatch(&buf, results))
+	require.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,251 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	optionalProjectParam = optionalParam{
+		Name: "project",
+	}
+	workflowFlag = flag.StringFlag{
+		Name:  "workflow",
+		Usage: "ID of a v2 workflow",
+	}
+	dryRunFlag = flag.BoolFlag{
+		Name:  "dry-run",
+		Usage: "show what would be done, without doing it",
+	}
+)
+
+// cancellation is a build or workflow that was (or would be) canceled.
+type cancellation struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	URL    string `json:"url,omitempty"`
+	DryRun bool   `json:"dry_run"`
+}
+
+func cancelCommand() cli.Command {
+	return cli.Command{
+		Name:  "cancel",
+		Usage: "Cancel a build, a workflow, the running workflows of a pipeline, or all running builds on a branch",
+		Flags: append([]flag.Flag{
+			optionalProjectParam,
+			buildFlag,
+			workflowFlag,
+			pipelineIDFlag,
+			branchFlag,
+			dryRunFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project  = ctx.String(optionalProjectParam.Name)
+				build    = ctx.String(buildFlag.Name)
+				workflow = ctx.String(workflowFlag.Name)
+				pipeline = ctx.String(pipelineIDFlag.Name)
+				branch   = ctx.String(branchFlag.Name)
+				dryRun   = ctx.Bool(dryRunFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			if err := checkCancelTarget(project, build, workflow, pipeline, branch); err != nil {
+				return err
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			var (
+				targets                                  []cancellation
+				projectVCS, projectUsername, projectName string
+			)
+
+			switch {
+			case workflow != "":
+				targets = []cancellation{{Type: "workflow", ID: workflow}}
+
+			case pipeline != "":
+				if targets, err = findWorkflows(ctx.Context(), client, pipeline); err != nil {
+					return err
+				}
+
+			default:
+				if projectVCS, projectUsername, projectName, err = resolveProject(ctx, project); err != nil {
+					return err
+				}
+
+				if targets, err = findBuilds(ctx.Context(), client, projectVCS, projectUsername, projectName, build, branch); err != nil {
+					return err
+				}
+			}
+
+			if !dryRun {
+				canceled, err := cancelTargets(ctx.Context(), client, projectVCS, projectUsername, projectName, targets)
+				if err != nil {
+					// Targets that were already canceled are still listed, so
+					// that it is clear which ones are left
+					if len(canceled) != 0 {
+						_ = printCancellations(ctx, out, canceled, false)
+					}
+
+					failed := targets[len(canceled)]
+					fmt.Fprintf(ctx.App.Stderr, "failed to cancel %s %s\n", failed.Type, failed.ID)
+
+					return err
+				}
+				targets = canceled
+			}
+
+			return printCancellations(ctx, out, targets, dryRun)
+		},
+	}
+}
+
+// checkCancelTarget checks that exactly one kind of target was given to
+// cancel. Builds belong to a project while workflows and pipelines do not,
+// and the project is inferred from the git remote if it is not given.
+func checkCancelTarget(project string, build string, workflow string, pipeline string, branch string) error {
+	switch {
+	case (workflow == "") != (pipeline == "") && build == "" && branch == "" && project == "":
+		return nil
+	case workflow == "" && pipeline == "" && (build == "") != (branch == ""):
+		return nil
+	default:
+		return errors.New("invalid flag combination")
+	}
+}
+
+// findBuilds returns the builds of the given project to cancel, which are
+// either the given build, or every running build on the given branch.
+func findBuilds(ctx context.Context, client cci.Client, vcs string, username string, project string, build string, branch string) ([]cancellation, error) {
+	if build != "" {
+		return []cancellation{{Type: "build", ID: build}}, nil
+	}
+
+	builds, err := client.ListBuildsContext(ctx, vcs, username, project, cci.ListBuildsOptions{
+		Branch: branch,
+		Filter: "running",
+		Limit:  100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]cancellation, 0, len(builds))
+	for _, running := range builds {
+		targets = append(targets, cancellation{
+			Type: "build",
+			ID:   strconv.Itoa(running.BuildNum),
+			URL:  running.BuildURL,
+		})
+	}
+
+	return targets, nil
+}
+
+// findWorkflows returns the workflows of the given pipeline to cancel, which
+// are those that have not finished yet, including those on hold.
+func findWorkflows(ctx context.Context, client cci.Client, pipeline string) ([]cancellation, error) {
+	workflows, err := client.GetPipelineWorkflowsContext(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]cancellation, 0, len(workflows))
+	for _, workflow := range workflows {
+		if workflow.Finished() {
+			continue
+		}
+
+		targets = append(targets, cancellation{Type: "workflow", ID: workflow.ID})
+	}
+
+	return targets, nil
+}
+
+// cancelTargets cancels each of the given builds or workflows in turn, and
+// returns those that were canceled. Builds belong to the given project. If a
+// target fails to be canceled, the targets that were canceled before it are
+// returned along with the error.
+func cancelTargets(ctx context.Context, client cci.Client, vcs string, username string, project string, targets []cancellation) ([]cancellation, error) {
+	canceled := make([]cancellation, 0, len(targets))
+
+	for _, target := range targets {
+		switch target.Type {
+		case "workflow":
+			if err := client.CancelWorkflowContext(ctx, target.ID); err != nil {
+				return canceled, err
+			}
+
+		default:
+			resp, err := client.CancelBuildContext(ctx, vcs, username, project, target.ID)
+			if err != nil {
+				return canceled, err
+			}
+			target.URL = resp.BuildURL
+		}
+
+		canceled = append(canceled, target)
+	}
+
+	return canceled, nil
+}
+
+// printCancellations prints the given builds or workflows, which were
+// canceled, or would be if dryRun is true.
+func printCancellations(ctx cli.Context, out printer, targets []cancellation, dryRun bool) error {
+	for index := range targets {
+		targets[index].DryRun = dryRun
+	}
+
+	return out.print(ctx.App.Stdout, targets, func(w io.Writer) error {
+		return renderCancellations(w, targets, dryRun)
+	})
+}
+
+// renderCancellations writes a line for each of the given builds or
+// workflows.
+func renderCancellations(w io.Writer, targets []cancellation, dryRun bool) error {
+	if len(targets) == 0 {
+		_, err := fmt.Fprintln(w, "nothing to cancel")
+		return err
+	}
+
+	verb := "canceled"
+	if dryRun {
+		verb = "would cancel"
+	}
+
+	for _, target := range targets {
+		line := fmt.Sprintf("%s %s %s", verb, target.Type, target.ID)
+		if target.URL != "" {
+			line += " " + target.URL
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
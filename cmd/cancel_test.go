@@ -0,0 +1,261 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestCheckCancelTarget(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		project  string
+		build    string
+		workflow string
+		pipeline string
+		branch   string
+		valid    bool
+	}{
+		{
+			title:   "build",
+			project: "alice/example",
+			build:   "123",
+			valid:   true,
+		},
+		{
+			title: "build in inferred project",
+			build: "123",
+			valid: true,
+		},
+		{
+			title:   "branch",
+			project: "alice/example",
+			branch:  "master",
+			valid:   true,
+		},
+		{
+			title:    "workflow",
+			workflow: "fda08377",
+			valid:    true,
+		},
+		{
+			title:    "pipeline",
+			pipeline: "5034460f",
+			valid:    true,
+		},
+		{
+			title: "nothing",
+		},
+		{
+			title:  "build and branch",
+			build:  "123",
+			branch: "master",
+		},
+		{
+			title:    "workflow and build",
+			build:    "123",
+			workflow: "fda08377",
+		},
+		{
+			title:    "workflow in project",
+			project:  "alice/example",
+			workflow: "fda08377",
+		},
+		{
+			title:    "pipeline and workflow",
+			workflow: "fda08377",
+			pipeline: "5034460f",
+		},
+		{
+			title:    "pipeline and branch",
+			pipeline: "5034460f",
+			branch:   "master",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			err := checkCancelTarget(test.project, test.build, test.workflow, test.pipeline, test.branch)
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, "invalid flag combination")
+			}
+		})
+	}
+}
+
+func TestFindBuilds(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		build   string
+		branch  string
+		listed  bool
+		targets []cancellation
+	}{
+		{
+			title: "single build",
+			build: "123",
+			targets: []cancellation{
+				{Type: "build", ID: "123"},
+			},
+		},
+		{
+			title:  "running builds on branch",
+			branch: "master",
+			listed: true,
+			targets: []cancellation{
+				{Type: "build", ID: "124", URL: "https://circleci.com/gh/alice/example/124"},
+				{Type: "build", ID: "123", URL: "https://circleci.com/gh/alice/example/123"},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var listed bool
+
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				listed = true
+
+				require.Equal(t, "GET", r.Method)
+				require.Equal(t, "/api/v1.1/project/github/alice/example/tree/master", r.URL.Path)
+				require.Equal(t, "running", r.URL.Query().Get("filter"))
+
+				fmt.Fprint(w, `[
+					{"build_num": 124, "build_url": "https://circleci.com/gh/alice/example/124"},
+					{"build_num": 123, "build_url": "https://circleci.com/gh/alice/example/123"}
+				]`)
+			})
+			defer done()
+
+			targets, err := findBuilds(context.Background(), client, "github", "alice", "example", test.build, test.branch)
+			require.NoError(t, err)
+			require.Equal(t, test.targets, targets)
+			require.Equal(t, test.listed, listed)
+		})
+	}
+}
+
+func TestFindWorkflows(t *testing.T) {
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/api/v2/pipeline/5034460f/workflow", r.URL.Path)
+
+		fmt.Fprint(w, `{"items": [
+			{"id": "w1", "name": "build", "status": "success"},
+			{"id": "w2", "name": "test", "status": "running"},
+			{"id": "w3", "name": "deploy", "status": "on_hold"},
+			{"id": "w4", "name": "lint", "status": "canceled"}
+		]}`)
+	})
+	defer done()
+
+	// Only the workflows that have not finished are canceled
+	targets, err := findWorkflows(context.Background(), client, "5034460f")
+	require.NoError(t, err)
+	require.Equal(t, []cancellation{
+		{Type: "workflow", ID: "w2"},
+		{Type: "workflow", ID: "w3"},
+	}, targets)
+}
+
+func TestCancelTargets(t *testing.T) {
+	var canceled []string
+
+	client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+
+		switch r.URL.Path {
+		case "/api/v1.1/project/github/alice/example/123/cancel":
+			canceled = append(canceled, "123")
+			fmt.Fprint(w, `{"build_num": 123, "build_url": "https://circleci.com/gh/alice/example/123"}`)
+		case "/api/v2/workflow/fda08377/cancel":
+			canceled = append(canceled, "fda08377")
+			fmt.Fprint(w, `{"message": "Accepted."}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Build not found"}`)
+		}
+	})
+	defer done()
+
+	targets := []cancellation{
+		{Type: "build", ID: "123"},
+		{Type: "workflow", ID: "fda08377"},
+		{Type: "build", ID: "124"},
+		{Type: "build", ID: "125"},
+	}
+
+	// The targets after the failed one are not canceled, and the error keeps
+	// its type so that a hint can be given for it
+	actual, err := cancelTargets(context.Background(), client, "github", "alice", "example", targets)
+	require.Error(t, err)
+	require.True(t, cci.IsNotFound(err))
+	require.Equal(t, []cancellation{
+		{Type: "build", ID: "123", URL: "https://circleci.com/gh/alice/example/123"},
+		{Type: "workflow", ID: "fda08377"},
+	}, actual)
+	require.Equal(t, []string{"123", "fda08377"}, canceled)
+}
+
+func TestRenderCancellations(t *testing.T) {
+	targets := []cancellation{
+		{Type: "build", ID: "123", URL: "https://circleci.com/gh/alice/example/123"},
+		{Type: "workflow", ID: "fda08377"},
+	}
+
+	tests := []struct {
+		title    string
+		targets  []cancellation
+		dryRun   bool
+		expected string
+	}{
+		{
+			title:   "canceled",
+			targets: targets,
+			expected: "" +
+				"canceled build 123 https://circleci.com/gh/alice/example/123\n" +
+				"canceled workflow fda08377\n",
+		},
+		{
+			title:   "dry run",
+			targets: targets,
+			dryRun:  true,
+			expected: "" +
+				"would cancel build 123 https://circleci.com/gh/alice/example/123\n" +
+				"would cancel workflow fda08377\n",
+		},
+		{
+			title:    "nothing to cancel",
+			dryRun:   true,
+			expected: "nothing to cancel\n",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, renderCancellations(&buf, test.targets, test.dryRun))
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	timeoutFlag = flag.DurationFlag{
+		Name:  "timeout",
+		Value: "60s",
+		Usage: "maximum time to wait for each CircleCI API request (0 to disable)",
+	}
+	retriesFlag = flag.IntFlag{
+		Name:  "retries",
+		Value: 3,
+		Usage: "number of times to retry rate limited or unavailable API requests",
+	}
+	retryMaxWaitFlag = flag.DurationFlag{
+		Name:  "retry-max-wait",
+		Value: "30s",
+		Usage: "maximum time to wait between retries",
+	}
+	verboseFlag = flag.BoolFlag{
+		Name:  "verbose",
+		Usage: "log retries, and each API request and its response status, to stderr",
+	}
+)
+
+// clientFlags are the flags used to configure the CircleCI client and to find
+// the project, which are shared by every command.
+var clientFlags = []flag.Flag{
+	configFlag,
+	profileFlag,
+	hostFlag,
+	tokenFromFlag,
+	authFlag,
+	remoteFlag,
+	timeoutFlag,
+	retriesFlag,
+	retryMaxWaitFlag,
+	verboseFlag,
+	debugFlag,
+}
+
+// newClient creates a CircleCI client configured from the working
+// environment, and from the values of clientFlags.
+func newClient(ctx cli.Context) (cci.Client, error) {
+	conf, err := loadSettings(ctx)
+	if err != nil {
+		return cci.Client{}, err
+	}
+
+	// An API token is required for operation
+	if conf.Token == nil {
+		if conf.Profile != "" {
+			return cci.Client{}, fmt.Errorf("no %s in working environment, and no token in profile %q", CircleTokenEnvVar, conf.Profile)
+		}
+		return cci.Client{}, fmt.Errorf("no %s in working environment", CircleTokenEnvVar)
+	}
+
+	token, err := conf.Token.Token(ctx.Context())
+	if err != nil {
+		return cci.Client{}, err
+	}
+
+	return configureClient(ctx, conf, token)
+}
+
+// tokenFromStdin reports whether the API token for the current command would
+// be read from stdin, either by the token-from flag or by the profile.
+func tokenFromStdin(ctx cli.Context) (bool, error) {
+	conf, err := loadSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, stdin := conf.Token.(stdinToken)
+	return stdin, nil
+}
+
+// configureClient creates a CircleCI client that uses the given settings and
+// API token, and the values of clientFlags.
+func configureClient(ctx cli.Context, conf settings, token string) (cci.Client, error) {
+	var (
+		timeout      = ctx.Duration(timeoutFlag.Name)
+		retries      = ctx.Int(retriesFlag.Name)
+		retryMaxWait = ctx.Duration(retryMaxWaitFlag.Name)
+		verbose      = ctx.Bool(verboseFlag.Name)
+		debug        = ctx.Bool(debugFlag.Name)
+	)
+
+	auth, err := cci.ParseAuthMethod(conf.Auth)
+	if err != nil {
+		return cci.Client{}, err
+	}
+
+	if retries < 0 {
+		return cci.Client{}, fmt.Errorf("invalid number of retries %d", retries)
+	}
+
+	policy := cci.RetryPolicy{
+		MaxAttempts: retries + 1,
+		Backoff:     time.Second,
+		MaxWait:     retryMaxWait,
+		Jitter:      0.5,
+	}
+
+	if verbose || debug {
+		policy.OnRetry = func(attempt int, wait time.Duration, err error) {
+			fmt.Fprintf(os.Stderr, "%s: attempt %d failed: %s\n", ctx.App.Name, attempt, err.Error())
+			fmt.Fprintf(os.Stderr, "%s: retrying in %s\n", ctx.App.Name, wait.Round(time.Millisecond))
+		}
+	}
+
+	client := cci.NewWithHost(token, conf.Host).
+		WithHTTPClient(&http.Client{Timeout: timeout}).
+		WithRetryPolicy(policy).
+		WithAuthMethod(auth)
+
+	if verbose || debug {
+		client = client.WithLogger(newTraceLogger(os.Stderr, ctx.App.Name, debug))
+	}
+
+	return client, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+// newTestClient starts a TLS test server using the given handler, and returns
+// a client that is configured to talk to it, without retrying failed requests.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (cci.Client, func()) {
+	server := httptest.NewTLSServer(handler)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	client := cci.NewWithHost("token", host).
+		WithHTTPClient(server.Client()).
+		WithRetryPolicy(cci.RetryPolicy{})
+
+	return client, server.Close
+}
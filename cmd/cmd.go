@@ -5,8 +5,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/flag"
@@ -18,6 +21,8 @@ const (
 	CircleTokenEnvVar = "CIRCLE_TOKEN"
 	CircleHostEnvVar  = "CIRCLE_HOST"
 	CirclePublicHost  = "circleci.com"
+	ConfigEnvVar      = "CCI_TRIGGER_CONFIG"
+	ProfileEnvVar     = "CCI_TRIGGER_PROFILE"
 )
 
 var (
@@ -54,52 +59,136 @@ func Cmd() *cli.App {
 	app.Version = Version()
 
 	app.Flags = []flag.Flag{
-		projectParam,
+		optionalProjectParam,
 		buildFlag,
 		sshFlag,
 		tagFlag,
 		branchFlag,
 		refFlag,
+		pipelineFlag,
+		waitFlag,
+		pollIntervalFlag,
+		allProjectsFlag,
+		projectGlobFlag,
+		yesFlag,
+		concurrencyFlag,
+		dryRunFlag,
+		paramsFileFlag,
+		paramsFromEnvFlag,
+		allowEmptyParamsFlag,
+		outputFlag,
 		buildParams,
 	}
 
+	app.Flags = append(app.Flags, clientFlags...)
+
 	app.ErrorHandler = func(ctx cli.Context, err error) int {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", app.Name, err.Error())
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "%s: hint: %s\n", app.Name, hint)
+		}
+		if exitCoder, ok := err.(cli.ExitCoder); ok {
+			return exitCoder.ExitCode()
+		}
 		return 1
 	}
 
+	addCommands(app,
+		waitCommand(),
+		statusCommand(),
+		cancelCommand(),
+		approveCommand(),
+		rerunCommand(),
+		artifactsCommand(),
+		logsCommand(),
+		listCommand(),
+		batchCommand(),
+	)
+
 	app.Action = func(ctx cli.Context) error {
 
 		var (
-			token   string
-			host    string
-			project = ctx.String(projectParam.Name)
+			project = ctx.String(optionalProjectParam.Name)
 			branch  = ctx.String(branchFlag.Name)
 			ref     = ctx.String(refFlag.Name)
 			tag     = ctx.String(tagFlag.Name)
 			build   = ctx.String(buildFlag.Name)
 			ssh     = ctx.Bool(sshFlag.Name)
+			wait    = ctx.Bool(waitFlag.Name)
+			output  = ctx.String(outputFlag.Name)
+			all     = ctx.Bool(allProjectsFlag.Name)
+			glob    = ctx.String(projectGlobFlag.Name)
 			params  = ctx.Slice(buildParams.Name)
 		)
 
-		// The CIRCLE_TOKEN environment variable is required for operation
-		token, found := os.LookupEnv(CircleTokenEnvVar)
-		if !found {
-			return fmt.Errorf("no %s in working environment", CircleTokenEnvVar)
+		// The project may be omitted to infer it from the git remote, in
+		// which case the first argument is already a parameter
+		if isParamArg(project) {
+			project, params = "", append([]string{project}, params...)
 		}
 
-		// The CIRCLE_HOST environment variable is optional, and overrides the default
-		host, found = os.LookupEnv(CircleHostEnvVar)
-		if !found {
-			host = CirclePublicHost
+		out, err := newPrinter(output)
+		if err != nil {
+			return err
+		}
+
+		// Trigger every followed project matching the selector, instead of a
+		// single project
+		if all || glob != "" {
+			if project != "" || wait || ctx.Bool(pipelineFlag.Name) || ctx.Bool(dryRunFlag.Name) {
+				return errors.New("invalid flag combination")
+			}
+
+			buildParams, err := loadParams(ctx, params)
+			if err != nil {
+				return err
+			}
+
+			action, err := getAction(build, ssh, tag, branch, ref, buildParams)
+			if err != nil {
+				return err
+			}
+
+			desc, handler := getHandler(action, build, ssh, tag, branch, ref, buildParams)
+
+			return triggerSelected(ctx, out, glob, ctx.Bool(yesFlag.Name), action, desc, handler)
 		}
 
-		projectVCS, projectUsername, ProjectName, err := splitProject(project)
+		projectVCS, projectUsername, ProjectName, projectBuild, err := resolveProjectBuild(ctx, project)
 		if err != nil {
 			return err
 		}
 
-		buildParams, err := splitParams(params)
+		// A build URL implies the build flag
+		if projectBuild != "" {
+			if build != "" && build != projectBuild {
+				return errors.New("invalid flag combination")
+			}
+			build = projectBuild
+		}
+
+		// When the project is inferred from the git remote, and nothing else
+		// is given to build, build the commit that is checked out
+		checkout := (project == "" || project == ".") && build == "" && tag == "" && branch == "" && ref == ""
+		if checkout {
+			if branch, ref, err = gitCheckout(ctx.Context()); err != nil {
+				return err
+			}
+		}
+
+		if ctx.Bool(pipelineFlag.Name) {
+			// Pipelines are triggered on the head of the checked out branch,
+			// rather than on the exact commit
+			if checkout {
+				if branch == "" {
+					return errors.New("cannot trigger a pipeline from a detached HEAD, use --branch or --tag")
+				}
+				ref = ""
+			}
+			return triggerPipeline(ctx, out, projectVCS, projectUsername, ProjectName, build, branch, ref, params)
+		}
+
+		buildParams, err := loadParams(ctx, params)
 		if err != nil {
 			return err
 		}
@@ -113,20 +202,70 @@ func Cmd() *cli.App {
 		// Get a readable description for the action
 		desc, handler := getHandler(action, build, ssh, tag, branch, ref, buildParams)
 		if handler == nil {
-			return fmt.Errorf(desc)
+			return errors.New(desc)
 		}
 
-		client := cci.NewWithHost(token, host)
+		// Print the request that would be sent, instead of sending it
+		if ctx.Bool(dryRunFlag.Name) {
+			return dryRunTrigger(ctx, out, desc, func(client cci.Client) error {
+				_, err := handler(ctx.Context(), client, projectVCS, projectUsername, ProjectName)
+				return err
+			})
+		}
+
+		client, err := newClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp, err := handler(ctx.Context(), client, projectVCS, projectUsername, ProjectName)
+		if err != nil {
+			return err
+		}
 
-		resp, err := handler(client, projectVCS, projectUsername, ProjectName)
+		err = out.print(ctx.App.Stdout, resp, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, resp.BuildURL)
+			return err
+		})
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(resp.BuildURL)
+		if !wait {
+			return nil
+		}
+
+		interval := ctx.Duration(pollIntervalFlag.Name)
+		build = strconv.Itoa(resp.BuildNum)
 
-		return nil
+		_, err = waitForBuild(ctx.Context(), app.Name, client, projectVCS, projectUsername, ProjectName, build, interval)
+		return err
 	}
 
 	return app
 }
+
+// helpFlag mirrors the flag that the cli package adds to every registered
+// command, as commands dispatched through Backcompat do not receive it.
+var helpFlag = flag.BoolFlag{
+	Name:  "help",
+	Alias: "h",
+	Usage: "print help and exit",
+}
+
+// addCommands registers the given subcommands with the app, adding the output
+// flag to each. Since the app's positional project parameter would otherwise
+// consume the command name, each command is also dispatched by name using
+// Backcompat.
+func addCommands(app *cli.App, commands ...cli.Command) {
+	for _, command := range commands {
+		command.Flags = append(append([]flag.Flag{}, command.Flags...), outputFlag)
+		app.Subcommands = append(app.Subcommands, command)
+
+		command.Flags = append(append([]flag.Flag{}, command.Flags...), helpFlag)
+		app.Backcompat = append(app.Backcompat, cli.Backcompat{
+			Path:    []string{command.Name},
+			Command: command,
+		})
+	}
+}
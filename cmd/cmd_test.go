@@ -0,0 +1,288 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/stretchr/testify/require"
+)
+
+// runApp runs the app with the given arguments and an empty config file, and
+// returns what it wrote to stdout, along with the error it failed with.
+func runApp(t *testing.T, args ...string) (string, error) {
+	return runAppWithConfig(t, "", args...)
+}
+
+// runAppWithConfig is like runApp, but with the given config file contents.
+func runAppWithConfig(t *testing.T, body string, args ...string) (string, error) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "config.yml")
+	require.NoError(t, ioutil.WriteFile(config, []byte(body), 0644))
+
+	var (
+		stdout, stderr bytes.Buffer
+		failure        error
+	)
+
+	app := Cmd()
+	app.Stdout, app.Stderr = &stdout, &stderr
+	app.ErrorHandler = func(ctx cli.Context, err error) int {
+		failure = err
+		return 1
+	}
+
+	app.Run(append([]string{app.Name, "--config", config}, args...))
+
+	return stdout.String(), failure
+}
+
+func TestPipelineTarget(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		args     []string
+		expected string
+		err      string
+	}{
+		{
+			title: "default branch",
+			args:  []string{"alice/example", "--pipeline", "--dry-run"},
+			expected: "" +
+				"trigger pipeline on default branch\n" +
+				"POST https://circleci.com/api/v2/project/gh/alice/example/pipeline\n" +
+				"{}\n",
+		},
+		{
+			title: "build flag",
+			args:  []string{"alice/example", "--build", "123", "--pipeline", "--dry-run"},
+			err:   "invalid flag combination",
+		},
+		{
+			title: "build url",
+			args:  []string{"https://circleci.com/gh/alice/example/123", "--pipeline", "--dry-run"},
+			err:   "invalid flag combination",
+		},
+		{
+			title: "ref flag",
+			args:  []string{"alice/example", "--ref", "2c9bd6df", "--pipeline", "--dry-run"},
+			err:   "invalid flag combination",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			stdout, err := runApp(t, test.args...)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, stdout)
+		})
+	}
+}
+
+func TestStdinConflicts(t *testing.T) {
+	// The token in the environment would be used instead of the profile
+	if token, found := os.LookupEnv(CircleTokenEnvVar); found {
+		os.Unsetenv(CircleTokenEnvVar)
+		defer os.Setenv(CircleTokenEnvVar, token)
+	}
+
+	const config = "" +
+		"default_profile: piped\n" +
+		"profiles:\n" +
+		"  piped:\n" +
+		"    token_from: stdin\n" +
+		"  stored:\n" +
+		"    token: token\n"
+
+	tests := []struct {
+		title    string
+		args     []string
+		expected string
+		err      string
+	}{
+		{
+			title: "param and token flag",
+			args:  []string{"alice/example", "DEPLOY=-", "--token-from", "stdin", "--profile", "stored", "--dry-run"},
+			err:   "the API token and a build parameter cannot both be read from stdin",
+		},
+		{
+			title: "param and token profile",
+			args:  []string{"alice/example", "DEPLOY=-", "--dry-run"},
+			err:   "the API token and a build parameter cannot both be read from stdin",
+		},
+		{
+			title: "manifest and token profile",
+			args:  []string{"batch"},
+			err:   "the API token and the manifest cannot both be read from stdin",
+		},
+		{
+			title: "param and stored token",
+			args:  []string{"alice/example", "DEPLOY=@@-", "--profile", "stored", "--dry-run"},
+			expected: "" +
+				"build default branch\n" +
+				"POST https://circleci.com/api/v1.1/project/github/alice/example\n" +
+				"{\n" +
+				"  \"build_parameters\": {\n" +
+				"    \"DEPLOY\": \"@-\"\n" +
+				"  }\n" +
+				"}\n",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			stdout, err := runAppWithConfig(t, config, test.args...)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, stdout)
+		})
+	}
+}
+
+// inGitRepo runs the given function in a new git repository, with an origin
+// remote of alice/example on GitHub, and master checked out at the returned
+// commit.
+func inGitRepo(t *testing.T, fn func(head string)) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(cwd)
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"symbolic-ref", "HEAD", "refs/heads/master"},
+		{"remote", "add", "origin", "git@github.com:alice/example.git"},
+		{"-c", "user.name=alice", "-c", "user.email=alice@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		_, err := git(context.Background(), args...)
+		require.NoError(t, err)
+	}
+
+	head, err := git(context.Background(), "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	fn(head)
+}
+
+func TestInferredProject(t *testing.T) {
+	inGitRepo(t, func(head string) {
+		tests := []struct {
+			title    string
+			detach   bool
+			args     []string
+			expected string
+			err      string
+		}{
+			{
+				title: "leading param",
+				args:  []string{"--dry-run", "DEPLOY=true"},
+				expected: "" +
+					"build branch master at " + head + "\n" +
+					"POST https://circleci.com/api/v1.1/project/github/alice/example/tree/master\n" +
+					"{\n" +
+					"  \"revision\": \"" + head + "\",\n" +
+					"  \"build_parameters\": {\n" +
+					"    \"DEPLOY\": \"true\"\n" +
+					"  }\n" +
+					"}\n",
+			},
+			{
+				title: "leading pipeline param",
+				args:  []string{"deploy:bool=true", "--pipeline", "--dry-run"},
+				expected: "" +
+					"trigger pipeline on branch master\n" +
+					"POST https://circleci.com/api/v2/project/gh/alice/example/pipeline\n" +
+					"{\n" +
+					"  \"branch\": \"master\",\n" +
+					"  \"parameters\": {\n" +
+					"    \"deploy\": true\n" +
+					"  }\n" +
+					"}\n",
+			},
+			{
+				title: "dot before params",
+				args:  []string{".", "DEPLOY=true", "--dry-run"},
+				expected: "" +
+					"build branch master at " + head + "\n" +
+					"POST https://circleci.com/api/v1.1/project/github/alice/example/tree/master\n" +
+					"{\n" +
+					"  \"revision\": \"" + head + "\",\n" +
+					"  \"build_parameters\": {\n" +
+					"    \"DEPLOY\": \"true\"\n" +
+					"  }\n" +
+					"}\n",
+			},
+			{
+				title:  "pipeline from detached head",
+				detach: true,
+				args:   []string{"--pipeline", "--dry-run"},
+				err:    "cannot trigger a pipeline from a detached HEAD, use --branch or --tag",
+			},
+			{
+				title:  "pipeline branch from detached head",
+				detach: true,
+				args:   []string{"--branch", "master", "--pipeline", "--dry-run"},
+				expected: "" +
+					"trigger pipeline on branch master\n" +
+					"POST https://circleci.com/api/v2/project/gh/alice/example/pipeline\n" +
+					"{\n" +
+					"  \"branch\": \"master\"\n" +
+					"}\n",
+			},
+		}
+
+		for index, test := range tests {
+			name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+			t.Run(name, func(t *testing.T) {
+				checkout := "master"
+				if test.detach {
+					checkout = "--detach"
+				}
+				_, err := git(context.Background(), "checkout", "-q", checkout)
+				require.NoError(t, err)
+
+				stdout, err := runApp(t, test.args...)
+
+				if test.err != "" {
+					require.EqualError(t, err, test.err)
+					return
+				}
+
+				require.NoError(t, err)
+				require.Equal(t, test.expected, stdout)
+			})
+		}
+	})
+}
@@ -0,0 +1,342 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	configFlag = flag.StringFlag{
+		Name:  "config",
+		Usage: "path to the config file (default ~/.config/cci-trigger/config.yml)",
+	}
+	profileFlag = flag.StringFlag{
+		Name:  "profile",
+		Usage: "name of the config file profile to use",
+	}
+	hostFlag = flag.StringFlag{
+		Name:  "host",
+		Usage: "CircleCI host to use, such as an enterprise instance",
+	}
+	authFlag = flag.StringFlag{
+		Name:  "auth",
+		Usage: "how to send the API token, one of header (the default), basic, or query for old enterprise instances",
+	}
+)
+
+// config is the contents of the config file.
+type config struct {
+	// DefaultProfile is the profile used when none is selected.
+	DefaultProfile string `yaml:"default_profile"`
+
+	// Profiles are the named profiles that can be selected.
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// profile is a named set of defaults for talking to a CircleCI host.
+type profile struct {
+	// Host is the CircleCI host, such as circleci.com.
+	Host string `yaml:"host"`
+
+	// Token is the API token itself. At most one of the token fields may be
+	// given.
+	Token string `yaml:"token"`
+
+	// TokenEnv is the name of an environment variable containing the API
+	// token.
+	TokenEnv string `yaml:"token_env"`
+
+	// TokenFile is the path of a file containing the API token, which must
+	// not be accessible by other users.
+	TokenFile string `yaml:"token_file"`
+
+	// TokenCommand is a shell command that prints the API token, such as a
+	// password manager.
+	TokenCommand string `yaml:"token_command"`
+
+	// TokenFrom is a token source in the same form as the token-from flag.
+	TokenFrom string `yaml:"token_from"`
+
+	// Auth is how the API token is sent, one of header, basic, or query.
+	Auth string `yaml:"auth"`
+
+	// VCS is the VCS used for projects that do not name one, such as github.
+	VCS string `yaml:"vcs"`
+
+	// Org is the username used for projects that do not name one.
+	Org string `yaml:"org"`
+}
+
+// settings are the resolved values used to talk to CircleCI, after combining
+// the selected profile, environment variables, and flags.
+type settings struct {
+	Profile string
+	Host    string
+	Token   tokenProvider
+	Auth    string
+	VCS     string
+	Org     string
+}
+
+// defaultConfigPath returns the path of the config file used when none is
+// given, which is inside $XDG_CONFIG_HOME or ~/.config.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := homeDir()
+		if home == "" {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "cci-trigger", "config.yml")
+}
+
+// homeDir returns the home directory of the current user, from $HOME or else
+// the user database, or an empty string if it cannot be found.
+func homeDir() string {
+	if home := os.Getenv("HOME"); home != "" {
+		return home
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return current.HomeDir
+}
+
+// loadConfig reads the config file at the given path. A missing file is not
+// an error unless required is true, and results in an empty config.
+func loadConfig(path string, required bool) (*config, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return &config{}, nil
+		}
+		return nil, fmt.Errorf("reading config file: %s", err.Error())
+	}
+
+	var cfg config
+	if err := yaml.UnmarshalStrict(body, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", path, err.Error())
+	}
+
+	return &cfg, nil
+}
+
+// loadSettings resolves the settings for the current command, from the
+// config file, the working environment, and the values of clientFlags.
+func loadSettings(ctx cli.Context) (settings, error) {
+	var (
+		path     = ctx.String(configFlag.Name)
+		name     = ctx.String(profileFlag.Name)
+		host     = ctx.String(hostFlag.Name)
+		from     = ctx.String(tokenFromFlag.Name)
+		auth     = ctx.String(authFlag.Name)
+		required = true
+	)
+
+	// The config file is only required to exist if it was named explicitly
+	if path == "" {
+		path = os.Getenv(ConfigEnvVar)
+	}
+	if path == "" {
+		path = defaultConfigPath()
+		required = false
+	}
+
+	cfg, err := loadConfig(path, required)
+	if err != nil {
+		return settings{}, err
+	}
+
+	if name == "" {
+		name = os.Getenv(ProfileEnvVar)
+	}
+
+	conf, err := resolveSettings(cfg, name, host, from, os.LookupEnv)
+	if err != nil {
+		return settings{}, err
+	}
+
+	// The auth flag overrides the profile
+	if auth != "" {
+		conf.Auth = auth
+	}
+
+	return conf, nil
+}
+
+// resolveSettings combines the named profile from the given config with the
+// environment and the host and token-from flags. Each setting is taken from
+// the first of these places that provides it:
+//
+//   - host:  the host flag, $CIRCLE_HOST, the profile, then circleci.com
+//   - token: the token-from flag, $CIRCLE_TOKEN, then the profile
+//   - auth:  the profile, then header
+//   - vcs:   the profile
+//   - org:   the profile
+//
+// The profile used is the named one, or else the config's default profile.
+// The token is not read until it is needed.
+func resolveSettings(cfg *config, name string, host string, from string, lookupEnv func(string) (string, bool)) (settings, error) {
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+
+	var selected profile
+	if name != "" {
+		var found bool
+		if selected, found = cfg.Profiles[name]; !found {
+			return settings{}, fmt.Errorf("unknown profile %q, expected one of %s", name, profileNames(cfg))
+		}
+	}
+
+	result := settings{
+		Profile: name,
+		Host:    host,
+		Auth:    selected.Auth,
+		VCS:     selected.VCS,
+		Org:     selected.Org,
+	}
+
+	if result.Host == "" {
+		result.Host, _ = lookupEnv(CircleHostEnvVar)
+	}
+	if result.Host == "" {
+		result.Host = selected.Host
+	}
+	if result.Host == "" {
+		result.Host = CirclePublicHost
+	}
+
+	if result.Auth == "" {
+		result.Auth = cci.AuthHeader.String()
+	}
+
+	var err error
+	if from != "" {
+		result.Token, err = parseTokenProvider(from)
+	} else if _, found := lookupEnv(CircleTokenEnvVar); found {
+		result.Token = envToken(CircleTokenEnvVar)
+	} else {
+		result.Token, err = selected.tokenProvider(name)
+	}
+	if err != nil {
+		return settings{}, err
+	}
+
+	return result, nil
+}
+
+// tokenProvider returns the provider for the API token of the profile with
+// the given name, or nil if the profile does not configure a token.
+func (selected profile) tokenProvider(name string) (tokenProvider, error) {
+	var providers []tokenProvider
+
+	if selected.Token != "" {
+		providers = append(providers, staticToken{selected.Token, fmt.Sprintf("profile %q", name)})
+	}
+	if selected.TokenEnv != "" {
+		providers = append(providers, envToken(selected.TokenEnv))
+	}
+	if selected.TokenFile != "" {
+		providers = append(providers, fileToken(selected.TokenFile))
+	}
+	if selected.TokenCommand != "" {
+		providers = append(providers, commandToken(selected.TokenCommand))
+	}
+	if selected.TokenFrom != "" {
+		provider, err := parseTokenProvider(selected.TokenFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %q: %s", name, err.Error())
+		}
+		providers = append(providers, provider)
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return providers[0], nil
+	default:
+		return nil, fmt.Errorf("invalid profile %q: only one of token, token_env, token_file, token_command, or token_from may be given", name)
+	}
+}
+
+// profileNames returns the sorted names of every profile in the given config.
+func profileNames(cfg *config) string {
+	if len(cfg.Profiles) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// resolveProject splits the given project name, filling in the VCS and
+// username from the selected profile if the name does not include them. If
+// the name is empty or ".", the project is inferred from the git remote of
+// the working directory instead.
+func resolveProject(ctx cli.Context, name string) (string, string, string, error) {
+	vcs, username, project, _, err := resolveProjectBuild(ctx, name)
+	return vcs, username, project, err
+}
+
+// resolveProjectBuild is like resolveProject, but also returns the build
+// number implied by a CircleCI build or job URL, if one was given.
+func resolveProjectBuild(ctx cli.Context, name string) (string, string, string, string, error) {
+	if name == "" || name == "." {
+		vcs, username, project, err := gitRemoteProject(ctx.Context(), ctx.String(remoteFlag.Name))
+		return vcs, username, project, "", err
+	}
+
+	conf, err := loadSettings(ctx)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	return splitProjectBuild(qualifyProject(conf, name))
+}
+
+// qualifyProject prefixes the given project name with the org and VCS from
+// the given settings, where the name does not already include them.
+func qualifyProject(conf settings, name string) string {
+	// URLs always include the VCS and username
+	if strings.Contains(name, ":") {
+		return name
+	}
+
+	if name != "" && !strings.Contains(name, "/") && conf.Org != "" {
+		name = conf.Org + "/" + name
+	}
+
+	if strings.Count(name, "/") == 1 && conf.VCS != "" {
+		name = conf.VCS + "/" + name
+	}
+
+	return name
+}
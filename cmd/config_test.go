@@ -0,0 +1,308 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	body := `
+default_profile: public
+profiles:
+  public:
+    token_env: PUBLIC_CIRCLE_TOKEN
+    org: alice
+  enterprise:
+    host: circleci.example.com
+    token: secret
+    vcs: bitbucket
+`
+	require.NoError(t, ioutil.WriteFile(path, []byte(body), 0600))
+
+	cfg, err := loadConfig(path, true)
+	require.NoError(t, err)
+	require.Equal(t, "public", cfg.DefaultProfile)
+	require.Equal(t, profile{TokenEnv: "PUBLIC_CIRCLE_TOKEN", Org: "alice"}, cfg.Profiles["public"])
+	require.Equal(t, profile{Host: "circleci.example.com", Token: "secret", VCS: "bitbucket"}, cfg.Profiles["enterprise"])
+
+	// A missing file is only an error if it is required
+	cfg, err = loadConfig(filepath.Join(dir, "missing.yml"), false)
+	require.NoError(t, err)
+	require.Empty(t, cfg.Profiles)
+
+	_, err = loadConfig(filepath.Join(dir, "missing.yml"), true)
+	require.Error(t, err)
+
+	// Unknown keys are rejected, so that typos are not silently ignored
+	require.NoError(t, ioutil.WriteFile(path, []byte("profiles:\n  public:\n    hots: example.com\n"), 0600))
+	_, err = loadConfig(path, true)
+	require.Error(t, err)
+}
+
+func TestResolveSettings(t *testing.T) {
+	cfg := &config{
+		DefaultProfile: "public",
+		Profiles: map[string]profile{
+			"public": {
+				TokenEnv: "PUBLIC_CIRCLE_TOKEN",
+				Org:      "alice",
+			},
+			"enterprise": {
+				Host:  "circleci.example.com",
+				Token: "enterprise-token",
+				VCS:   "bitbucket",
+			},
+		},
+	}
+
+	tests := []struct {
+		title    string
+		config   *config
+		profile  string
+		host     string
+		from     string
+		env      map[string]string
+		settings settings
+		err      string
+	}{
+		{
+			title:  "no config",
+			config: &config{},
+			env:    map[string]string{"CIRCLE_TOKEN": "env-token"},
+			settings: settings{
+				Host:  "circleci.com",
+				Auth:  "header",
+				Token: envToken("CIRCLE_TOKEN"),
+			},
+		},
+		{
+			title:  "no config with host from env",
+			config: &config{},
+			env:    map[string]string{"CIRCLE_TOKEN": "env-token", "CIRCLE_HOST": "circleci.env.com"},
+			settings: settings{
+				Host:  "circleci.env.com",
+				Auth:  "header",
+				Token: envToken("CIRCLE_TOKEN"),
+			},
+		},
+		{
+			title:  "default profile",
+			config: cfg,
+			env:    map[string]string{"PUBLIC_CIRCLE_TOKEN": "public-token"},
+			settings: settings{
+				Profile: "public",
+				Host:    "circleci.com",
+				Auth:    "header",
+				Token:   envToken("PUBLIC_CIRCLE_TOKEN"),
+				Org:     "alice",
+			},
+		},
+		{
+			title: "auth from profile",
+			config: &config{
+				Profiles: map[string]profile{"legacy": {Auth: "query"}},
+			},
+			profile: "legacy",
+			env:     map[string]string{"CIRCLE_TOKEN": "env-token"},
+			settings: settings{
+				Profile: "legacy",
+				Host:    "circleci.com",
+				Auth:    "query",
+				Token:   envToken("CIRCLE_TOKEN"),
+			},
+		},
+		{
+			title:   "named profile",
+			config:  cfg,
+			profile: "enterprise",
+			settings: settings{
+				Profile: "enterprise",
+				Host:    "circleci.example.com",
+				Auth:    "header",
+				Token:   staticToken{"enterprise-token", `profile "enterprise"`},
+				VCS:     "bitbucket",
+			},
+		},
+		{
+			title:   "env overrides profile",
+			config:  cfg,
+			profile: "enterprise",
+			env:     map[string]string{"CIRCLE_TOKEN": "env-token", "CIRCLE_HOST": "circleci.env.com"},
+			settings: settings{
+				Profile: "enterprise",
+				Host:    "circleci.env.com",
+				Auth:    "header",
+				Token:   envToken("CIRCLE_TOKEN"),
+				VCS:     "bitbucket",
+			},
+		},
+		{
+			title:   "flag overrides env",
+			config:  cfg,
+			profile: "enterprise",
+			host:    "circleci.flag.com",
+			env:     map[string]string{"CIRCLE_HOST": "circleci.env.com"},
+			settings: settings{
+				Profile: "enterprise",
+				Host:    "circleci.flag.com",
+				Auth:    "header",
+				Token:   staticToken{"enterprise-token", `profile "enterprise"`},
+				VCS:     "bitbucket",
+			},
+		},
+		{
+			title:   "unknown profile",
+			config:  cfg,
+			profile: "missing",
+			err:     `unknown profile "missing", expected one of enterprise, public`,
+		},
+		{
+			title:   "token from flag overrides env",
+			config:  cfg,
+			profile: "enterprise",
+			from:    "command:pass show circleci",
+			env:     map[string]string{"CIRCLE_TOKEN": "env-token"},
+			settings: settings{
+				Profile: "enterprise",
+				Host:    "circleci.example.com",
+				Auth:    "header",
+				Token:   commandToken("pass show circleci"),
+				VCS:     "bitbucket",
+			},
+		},
+		{
+			title: "token file from profile",
+			config: &config{
+				Profiles: map[string]profile{"file": {TokenFile: "~/.circleci/token"}},
+			},
+			profile: "file",
+			settings: settings{
+				Profile: "file",
+				Host:    "circleci.com",
+				Auth:    "header",
+				Token:   fileToken("~/.circleci/token"),
+			},
+		},
+		{
+			title: "several tokens in profile",
+			config: &config{
+				Profiles: map[string]profile{"both": {Token: "secret", TokenCommand: "pass show circleci"}},
+			},
+			profile: "both",
+			err:     `invalid profile "both": only one of token, token_env, token_file, token_command, or token_from may be given`,
+		},
+		{
+			title:  "invalid token from flag",
+			config: &config{},
+			from:   "keyring",
+			err:    `invalid token source "keyring", expected one of env:<name>, file:<path>, command:<command>, or stdin`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, found := test.env[key]
+				return value, found
+			}
+
+			actual, err := resolveSettings(test.config, test.profile, test.host, test.from, lookupEnv)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.settings, actual)
+		})
+	}
+}
+
+func TestQualifyProject(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		settings settings
+		name     string
+		expected string
+	}{
+		{
+			title:    "no defaults",
+			name:     "example",
+			expected: "example",
+		},
+		{
+			title:    "default org",
+			settings: settings{Org: "alice"},
+			name:     "example",
+			expected: "alice/example",
+		},
+		{
+			title:    "default org and vcs",
+			settings: settings{Org: "alice", VCS: "bitbucket"},
+			name:     "example",
+			expected: "bitbucket/alice/example",
+		},
+		{
+			title:    "explicit org",
+			settings: settings{Org: "alice", VCS: "bitbucket"},
+			name:     "bob/example",
+			expected: "bitbucket/bob/example",
+		},
+		{
+			title:    "remote url",
+			settings: settings{Org: "alice", VCS: "bitbucket"},
+			name:     "git@github.com:bob/example.git",
+			expected: "git@github.com:bob/example.git",
+		},
+		{
+			title:    "explicit vcs",
+			settings: settings{Org: "alice", VCS: "bitbucket"},
+			name:     "gh/bob/example",
+			expected: "gh/bob/example",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.expected, qualifyProject(test.settings, test.name))
+		})
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	for _, name := range []string{"XDG_CONFIG_HOME", "HOME"} {
+		old, found := os.LookupEnv(name)
+		if found {
+			defer os.Setenv(name, old)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	os.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	require.Equal(t, filepath.Join("/tmp/xdg", "cci-trigger", "config.yml"), defaultConfigPath())
+
+	os.Unsetenv("XDG_CONFIG_HOME")
+	os.Setenv("HOME", "/home/alice")
+	require.Equal(t, filepath.Join("/home/alice", ".config", "cci-trigger", "config.yml"), defaultConfigPath())
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/palantir/pkg/cli"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+// redactedToken is the API token used for dry runs, so that the real token
+// is never read, and can never appear in the printed request.
+const redactedToken = "REDACTED"
+
+// errDryRun is returned by dryRunTransport in place of a response.
+var errDryRun = errors.New("request not sent in dry run")
+
+// dryRunRequest is a request that would have been sent to CircleCI.
+type dryRunRequest struct {
+	Action string          `json:"action"`
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// dryRunTransport records the first request made through it, instead of
+// sending it.
+type dryRunTransport struct {
+	request *dryRunRequest
+}
+
+func (transport *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport.request == nil {
+		recorded := dryRunRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+		}
+
+		if req.Body != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			recorded.Body = body
+		}
+
+		transport.request = &recorded
+	}
+
+	return nil, errDryRun
+}
+
+// dryRunTrigger calls the given trigger with a client that records the
+// request it makes instead of sending it, and then prints the request along
+// with the given description of the action.
+func dryRunTrigger(ctx cli.Context, out printer, description string, trigger func(client cci.Client) error) error {
+	conf, err := loadSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := configureClient(ctx, conf, redactedToken)
+	if err != nil {
+		return err
+	}
+
+	transport := &dryRunTransport{}
+	client = client.
+		WithTransport(transport).
+		WithRetryPolicy(cci.RetryPolicy{})
+
+	// Errors raised before a request is made, such as an unsupported VCS,
+	// are still reported
+	if err := trigger(client); transport.request == nil {
+		if err == nil {
+			err = errors.New("no request was made")
+		}
+		return err
+	}
+
+	request := *transport.request
+	request.Action = description
+
+	return out.print(ctx.App.Stdout, request, func(w io.Writer) error {
+		return renderDryRun(w, request)
+	})
+}
+
+// renderDryRun writes the description, method, URL, and indented JSON body
+// of the given request.
+func renderDryRun(w io.Writer, request dryRunRequest) error {
+	fmt.Fprintln(w, request.Action)
+	fmt.Fprintf(w, "%s %s\n", request.Method, request.URL)
+
+	if len(request.Body) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	if err := json.Indent(&body, request.Body, "", "  "); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, body.String())
+	return err
+}
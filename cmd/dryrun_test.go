@@ -0,0 +1,89 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestDryRunTransport(t *testing.T) {
+	tests := []struct {
+		title    string
+		auth     cci.AuthMethod
+		trigger  func(cci.Client) error
+		expected string
+	}{
+		{
+			title: "build branch",
+			auth:  cci.AuthHeader,
+			trigger: func(client cci.Client) error {
+				_, err := client.BuildBranch("github", "alice", "example", "master", map[string]string{"B": "2", "A": "1"})
+				return err
+			},
+			expected: "" +
+				"build branch master\n" +
+				"POST https://circleci.com/api/v1.1/project/github/alice/example/tree/master\n" +
+				"{\n" +
+				"  \"build_parameters\": {\n" +
+				"    \"A\": \"1\",\n" +
+				"    \"B\": \"2\"\n" +
+				"  }\n" +
+				"}\n",
+		},
+		{
+			title: "query auth",
+			auth:  cci.AuthQuery,
+			trigger: func(client cci.Client) error {
+				_, err := client.BuildTag("github", "alice", "example", "v1.0.0", nil)
+				return err
+			},
+			expected: "" +
+				"build branch master\n" +
+				"POST https://circleci.com/api/v1.1/project/github/alice/example?circle-token=REDACTED\n" +
+				"{\n" +
+				"  \"tag\": \"v1.0.0\"\n" +
+				"}\n",
+		},
+		{
+			title: "no body",
+			auth:  cci.AuthHeader,
+			trigger: func(client cci.Client) error {
+				_, err := client.GetBuild("github", "alice", "example", "123")
+				return err
+			},
+			expected: "" +
+				"build branch master\n" +
+				"GET https://circleci.com/api/v1.1/project/github/alice/example/123\n",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			transport := &dryRunTransport{}
+			client := cci.New(redactedToken).
+				WithAuthMethod(test.auth).
+				WithTransport(transport)
+
+			err := test.trigger(client)
+			require.Error(t, err)
+			require.NotNil(t, transport.request)
+
+			request := *transport.request
+			request.Action = "build branch master"
+
+			var buf bytes.Buffer
+			require.NoError(t, renderDryRun(&buf, request))
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+// errorHint returns a suggestion for resolving the given error, or an empty
+// string if there is nothing useful to suggest.
+func errorHint(err error) string {
+	switch {
+	case cci.IsUnauthorized(err):
+		return fmt.Sprintf("check that %s, or the token of the selected profile, contains a valid API token", CircleTokenEnvVar)
+	case cci.IsForbidden(err):
+		return "check that your API token has permission to build this project"
+	case cci.IsNotFound(err):
+		return "check that the project name is correct, that the project is followed on CircleCI, and that the branch or build exists"
+	case cci.IsUnsupportedVCS(err):
+		return "this project only supports v2 API actions, such as --pipeline, list --pipelines, status, and rerun"
+	case cci.IsRateLimited(err):
+		return "the CircleCI API rate limit was exceeded, wait a while before trying again"
+	default:
+		return ""
+	}
+}
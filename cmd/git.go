@@ -0,0 +1,115 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/palantir/pkg/cli/flag"
+)
+
+var remoteFlag = flag.StringFlag{
+	Name:  "remote",
+	Value: "origin",
+	Usage: "git remote used to find the project when none is given",
+}
+
+// git runs git with the given arguments in the working directory, and
+// returns its trimmed output.
+func git(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), message)
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), err.Error())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitRemoteProject finds the project for the given git remote of the
+// repository in the working directory.
+func gitRemoteProject(ctx context.Context, remote string) (string, string, string, error) {
+	remoteURL, err := git(ctx, "config", "--get", fmt.Sprintf("remote.%s.url", remote))
+	if err != nil || remoteURL == "" {
+		return "", "", "", fmt.Errorf("no project given, and no git remote %q to infer it from", remote)
+	}
+
+	return splitRemoteURL(remoteURL)
+}
+
+// gitCheckout returns the branch and commit checked out in the working
+// directory. The branch is empty if HEAD is detached.
+func gitCheckout(ctx context.Context) (string, string, error) {
+	head, err := git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+
+	// symbolic-ref fails when HEAD is detached, which is not an error here
+	branch, _ := git(ctx, "symbolic-ref", "--short", "-q", "HEAD")
+
+	return branch, head, nil
+}
+
+// splitRemoteURL converts a GitHub, Bitbucket, or GitLab git remote URL into
+// a vcs, username, and project. SSH remotes (git@github.com:username/project.git
+// or ssh://git@github.com/username/project.git) and HTTPS remotes
+// (https://github.com/username/project.git) are supported.
+func splitRemoteURL(remote string) (string, string, string, error) {
+	var host, path string
+
+	switch {
+	case strings.Contains(remote, "://"):
+		parsed, err := url.Parse(remote)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid git remote %q", remote)
+		}
+		host, path = parsed.Hostname(), parsed.Path
+
+	case strings.Contains(remote, ":"):
+		// An scp-like address, such as git@github.com:username/project.git
+		chunks := strings.SplitN(remote, ":", 2)
+		host, path = chunks[0], chunks[1]
+		if index := strings.LastIndex(host, "@"); index != -1 {
+			host = host[index+1:]
+		}
+
+	default:
+		return "", "", "", fmt.Errorf("invalid git remote %q", remote)
+	}
+
+	var vcs string
+	switch strings.ToLower(host) {
+	case "github.com":
+		vcs = "github"
+	case "bitbucket.org":
+		vcs = "bitbucket"
+	case "gitlab.com":
+		vcs = "gitlab"
+	default:
+		return "", "", "", fmt.Errorf("unsupported git remote %q, only GitHub, Bitbucket, and GitLab remotes are supported", remote)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+
+	chunks := strings.Split(path, "/")
+	if len(chunks) != 2 || chunks[0] == "" || chunks[1] == "" {
+		return "", "", "", fmt.Errorf("invalid git remote %q", remote)
+	}
+
+	return vcs, chunks[0], chunks[1], nil
+}
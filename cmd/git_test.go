@@ -0,0 +1,107 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRemoteURL(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		remote   string
+		vcs      string
+		username string
+		project  string
+		err      string
+	}{
+		{
+			title:    "github ssh",
+			remote:   "git@github.com:joshdk/cci-trigger.git",
+			vcs:      "github",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "github ssh url",
+			remote:   "ssh://git@github.com/joshdk/cci-trigger.git",
+			vcs:      "github",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "github https",
+			remote:   "https://github.com/joshdk/cci-trigger.git",
+			vcs:      "github",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "github https without suffix",
+			remote:   "https://github.com/joshdk/cci-trigger/",
+			vcs:      "github",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "bitbucket ssh",
+			remote:   "git@bitbucket.org:joshdk/cci-trigger.git",
+			vcs:      "bitbucket",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "bitbucket https with user",
+			remote:   "https://joshdk@bitbucket.org/joshdk/cci-trigger.git",
+			vcs:      "bitbucket",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:    "gitlab ssh",
+			remote:   "git@gitlab.com:joshdk/cci-trigger.git",
+			vcs:      "gitlab",
+			username: "joshdk",
+			project:  "cci-trigger",
+		},
+		{
+			title:  "unsupported host",
+			remote: "git@example.com:joshdk/cci-trigger.git",
+			err:    `unsupported git remote "git@example.com:joshdk/cci-trigger.git", only GitHub, Bitbucket, and GitLab remotes are supported`,
+		},
+		{
+			title:  "missing project",
+			remote: "https://github.com/joshdk",
+			err:    `invalid git remote "https://github.com/joshdk"`,
+		},
+		{
+			title:  "local path",
+			remote: "/srv/git/cci-trigger.git",
+			err:    `invalid git remote "/srv/git/cci-trigger.git"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			vcs, username, project, err := splitRemoteURL(test.remote)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.vcs, vcs)
+			require.Equal(t, test.username, username)
+			require.Equal(t, test.project, project)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	pipelinesFlag = flag.BoolFlag{
+		Name:  "pipelines",
+		Usage: "list v2 pipelines, instead of v1.1 builds",
+	}
+	listBranchFlag = flag.StringFlag{
+		Name:  "branch",
+		Usage: "only list builds or pipelines on the given branch",
+	}
+	statusFlag = flag.StringFlag{
+		Name:  "status",
+		Usage: "only list builds with the given status, one of completed, successful, failed, or running, or pipelines in the given state",
+	}
+	userFlag = flag.StringFlag{
+		Name:  "user",
+		Usage: "only list builds or pipelines triggered by the user with the given login",
+	}
+	limitFlag = flag.IntFlag{
+		Name:  "limit",
+		Value: 30,
+		Usage: "maximum number of builds or pipelines to list",
+	}
+)
+
+func listCommand() cli.Command {
+	return cli.Command{
+		Name:  "list",
+		Usage: "List the most recent builds or pipelines of a project",
+		Flags: append([]flag.Flag{
+			optionalProjectParam,
+			pipelinesFlag,
+			listBranchFlag,
+			statusFlag,
+			userFlag,
+			limitFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project   = ctx.String(optionalProjectParam.Name)
+				pipelines = ctx.Bool(pipelinesFlag.Name)
+				branch    = ctx.String(listBranchFlag.Name)
+				status    = ctx.String(statusFlag.Name)
+				user      = ctx.String(userFlag.Name)
+				limit     = ctx.Int(limitFlag.Name)
+				output    = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			if limit < 1 {
+				return fmt.Errorf("invalid limit %d", limit)
+			}
+
+			projectVCS, projectUsername, projectName, err := resolveProject(ctx, project)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			if pipelines {
+				resp, err := client.ListPipelinesContext(ctx.Context(), projectVCS, projectUsername, projectName, cci.ListPipelinesOptions{
+					Branch: branch,
+					State:  status,
+					User:   user,
+					Limit:  limit,
+				})
+				if err != nil {
+					return err
+				}
+
+				return out.print(ctx.App.Stdout, resp, func(w io.Writer) error {
+					return renderPipelines(w, resp)
+				})
+			}
+
+			switch status {
+			case "", "completed", "successful", "failed", "running":
+			default:
+				return fmt.Errorf("invalid build status %q", status)
+			}
+
+			resp, err := client.ListBuildsContext(ctx.Context(), projectVCS, projectUsername, projectName, cci.ListBuildsOptions{
+				Branch: branch,
+				Filter: status,
+				User:   user,
+				Limit:  limit,
+			})
+			if err != nil {
+				return err
+			}
+
+			return out.print(ctx.App.Stdout, resp, func(w io.Writer) error {
+				return renderBuilds(w, resp, time.Now())
+			})
+		},
+	}
+}
+
+// renderBuilds writes the given builds as a table.
+func renderBuilds(w io.Writer, builds []cci.Build, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "BUILD\tBRANCH\tSTATUS\tUSER\tQUEUED\tDURATION")
+
+	for _, build := range builds {
+		login := "-"
+		if build.User != nil && build.User.Login != "" {
+			login = build.User.Login
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", build.BuildNum, orDash(build.Branch), build.Status, login, timestamp(build.QueuedAt), duration(build.StartTime, build.StopTime, now))
+	}
+
+	return tw.Flush()
+}
+
+// renderPipelines writes the given pipelines as a table.
+func renderPipelines(w io.Writer, pipelines []cci.Pipeline) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PIPELINE\tBRANCH\tSTATE\tUSER\tCREATED\tID")
+
+	for _, pipeline := range pipelines {
+		branch := "-"
+		if pipeline.VCS != nil {
+			branch = orDash(pipeline.VCS.Branch)
+			if pipeline.VCS.Tag != "" {
+				branch = "tag " + pipeline.VCS.Tag
+			}
+		}
+
+		login := "-"
+		if pipeline.Trigger != nil {
+			login = orDash(pipeline.Trigger.Actor.Login)
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", pipeline.Number, branch, pipeline.State, login, timestamp(pipeline.CreatedAt), pipeline.ID)
+	}
+
+	return tw.Flush()
+}
+
+// timestamp formats the given time for display in a table.
+func timestamp(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// orDash returns the given value, or a dash if it is empty.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
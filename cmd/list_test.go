@@ -0,0 +1,60 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestRenderBuilds(t *testing.T) {
+	at := func(minutes int) *time.Time {
+		t := time.Date(2017, 10, 1, 12, minutes, 0, 0, time.UTC)
+		return &t
+	}
+
+	builds := []cci.Build{
+		{BuildNum: 124, Branch: "feature", Status: "running", User: &cci.BuildUser{Login: "bob"}, QueuedAt: at(4), StartTime: at(4)},
+		{BuildNum: 123, Branch: "master", Status: "success", User: &cci.BuildUser{Login: "alice"}, QueuedAt: at(0), StartTime: at(1), StopTime: at(3)},
+		{BuildNum: 122, Status: "not_run"},
+	}
+
+	expected := "" +
+		"BUILD  BRANCH   STATUS   USER   QUEUED               DURATION\n" +
+		"124    feature  running  bob    2017-10-01 12:04:00  1m0s\n" +
+		"123    master   success  alice  2017-10-01 12:00:00  2m0s\n" +
+		"122    -        not_run  -      -                    -\n"
+
+	var buf bytes.Buffer
+	err := renderBuilds(&buf, builds, *at(5))
+
+	require.NoError(t, err)
+	require.Equal(t, expected, buf.String())
+}
+
+func TestRenderPipelines(t *testing.T) {
+	created := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	pipelines := []cci.Pipeline{
+		{ID: "5034460f", Number: 26, State: "created", CreatedAt: &created, VCS: &cci.PipelineVCS{Tag: "v1.0.0"}},
+		{ID: "0d3e1f5a", Number: 25, State: "errored", VCS: &cci.PipelineVCS{Branch: "master"}},
+	}
+
+	expected := "" +
+		"PIPELINE  BRANCH      STATE    USER  CREATED              ID\n" +
+		"26        tag v1.0.0  created  -     2017-10-01 12:00:00  5034460f\n" +
+		"25        master      errored  -     -                    0d3e1f5a\n"
+
+	var buf bytes.Buffer
+	err := renderPipelines(&buf, pipelines)
+
+	require.NoError(t, err)
+	require.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,219 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	stepFlag = flag.StringFlag{
+		Name:  "step",
+		Usage: "name of a step to show, may be given multiple times",
+	}
+	failedOnlyFlag = flag.BoolFlag{
+		Name:  "failed-only",
+		Usage: "only show steps that failed",
+	}
+	followFlag = flag.BoolFlag{
+		Name:  "follow",
+		Alias: "f",
+		Usage: "keep showing steps as they finish until the build finishes, and exit with its outcome",
+	}
+)
+
+// stepLog is the output of a single build step, as run on a single parallel
+// node.
+type stepLog struct {
+	Name   string `json:"name"`
+	Step   int    `json:"step"`
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Output string `json:"output"`
+}
+
+func logsCommand() cli.Command {
+	return cli.Command{
+		Name:  "logs",
+		Usage: "Show the output of each step of a build",
+		Flags: append([]flag.Flag{
+			projectParam,
+			buildNumParam,
+			stepFlag,
+			failedOnlyFlag,
+			followFlag,
+			pollIntervalFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project    = ctx.String(projectParam.Name)
+				build      = ctx.String(buildNumParam.Name)
+				failedOnly = ctx.Bool(failedOnlyFlag.Name)
+				follow     = ctx.Bool(followFlag.Name)
+				interval   = ctx.Duration(pollIntervalFlag.Name)
+				output     = ctx.String(outputFlag.Name)
+				steps      []string
+			)
+
+			// The step flag may be given any number of times
+			if ctx.Has(stepFlag.Name) {
+				steps = ctx.StringSlice(stepFlag.Name)
+			}
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			projectVCS, projectUsername, projectName, err := resolveProject(ctx, project)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			var (
+				logs  = []stepLog{}
+				shown = map[[2]int]bool{}
+			)
+
+			for {
+				resp, err := client.GetBuildContext(ctx.Context(), projectVCS, projectUsername, projectName, build)
+				if err != nil {
+					return err
+				}
+
+				for _, step := range resp.Steps {
+					for _, action := range step.Actions {
+						key := [2]int{action.Step, action.Index}
+
+						// Steps are shown once they have finished, so that their
+						// output is complete
+						if shown[key] || !(action.Finished() || resp.Finished()) {
+							continue
+						}
+						shown[key] = true
+
+						if !matchStep(steps, step.Name) || failedOnly && !actionFailed(action) {
+							continue
+						}
+
+						chunks, err := client.GetActionOutputContext(ctx.Context(), action)
+						if err != nil {
+							return err
+						}
+
+						log := newStepLog(step.Name, action, chunks)
+
+						// Text is written as each step is fetched, while the other
+						// formats are written once every step has been collected
+						if out.format == "text" {
+							if err := log.render(ctx.App.Stdout, resp.Parallel > 1); err != nil {
+								return err
+							}
+						}
+
+						logs = append(logs, log)
+					}
+				}
+
+				if resp.Finished() || !follow {
+					err := out.print(ctx.App.Stdout, logs, func(w io.Writer) error {
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if follow {
+						return outcomeError(build, resp.Outcome)
+					}
+					return nil
+				}
+
+				select {
+				case <-ctx.Context().Done():
+					return ctx.Context().Err()
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+}
+
+// matchStep reports whether the step with the given name should be shown. All
+// steps are shown if no names are given.
+func matchStep(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// actionFailed reports whether the given action did not succeed.
+func actionFailed(action cci.BuildAction) bool {
+	if action.Failed || action.Timedout {
+		return true
+	}
+
+	switch action.Status {
+	case "failed", "timedout", "infrastructure_fail":
+		return true
+	default:
+		return false
+	}
+}
+
+// newStepLog combines the output of the given action into a single stepLog.
+func newStepLog(name string, action cci.BuildAction, output []cci.ActionOutput) stepLog {
+	var buf bytes.Buffer
+	for _, chunk := range output {
+		buf.WriteString(chunk.Message)
+	}
+
+	return stepLog{
+		Name:   name,
+		Step:   action.Step,
+		Index:  action.Index,
+		Status: action.Status,
+		Output: strings.Replace(buf.String(), "\r\n", "\n", -1),
+	}
+}
+
+// render writes the output of the step, below a header naming the step. The
+// parallel node is included in the header if the build ran on several nodes.
+func (log stepLog) render(w io.Writer, parallel bool) error {
+	header := fmt.Sprintf("==> %s (%s)", log.Name, log.Status)
+	if parallel {
+		header = fmt.Sprintf("==> %s [node %d] (%s)", log.Name, log.Index, log.Status)
+	}
+
+	output := log.Output
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+
+	_, err := fmt.Fprintf(w, "%s\n%s", header, output)
+	return err
+}
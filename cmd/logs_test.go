@@ -0,0 +1,97 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestStepLogRender(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		action   cci.BuildAction
+		output   []cci.ActionOutput
+		parallel bool
+		expected string
+	}{
+		{
+			title:    "no output",
+			action:   cci.BuildAction{Status: "success"},
+			expected: "==> build (success)\n",
+		},
+		{
+			title:  "combined output",
+			action: cci.BuildAction{Status: "failed"},
+			output: []cci.ActionOutput{
+				{Type: "out", Message: "hello\r\n"},
+				{Type: "err", Message: "world"},
+			},
+			expected: "==> build (failed)\nhello\nworld\n",
+		},
+		{
+			title:    "parallel node",
+			action:   cci.BuildAction{Status: "success", Index: 2},
+			output:   []cci.ActionOutput{{Type: "out", Message: "hello\n"}},
+			parallel: true,
+			expected: "==> build [node 2] (success)\nhello\n",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			log := newStepLog("build", test.action, test.output)
+			require.NoError(t, log.render(&buf, test.parallel))
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestActionFailed(t *testing.T) {
+
+	tests := []struct {
+		title  string
+		action cci.BuildAction
+		failed bool
+	}{
+		{
+			title:  "success",
+			action: cci.BuildAction{Status: "success"},
+		},
+		{
+			title:  "failed status",
+			action: cci.BuildAction{Status: "failed"},
+			failed: true,
+		},
+		{
+			title:  "failed flag",
+			action: cci.BuildAction{Status: "canceled", Failed: true},
+			failed: true,
+		},
+		{
+			title:  "timed out",
+			action: cci.BuildAction{Status: "timedout"},
+			failed: true,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.failed, actionFailed(test.action))
+		})
+	}
+}
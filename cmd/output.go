@@ -0,0 +1,103 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/palantir/pkg/cli/flag"
+	"gopkg.in/yaml.v2"
+)
+
+const templatePrefix = "template="
+
+var outputFlag = flag.StringFlag{
+	Name:  "output",
+	Alias: "o",
+	Value: "text",
+	Usage: "output format, one of text, json, yaml, or template=<go template>",
+}
+
+// printer renders command results in the format selected by outputFlag.
+type printer struct {
+	format   string
+	template *template.Template
+}
+
+// newPrinter creates a printer for the given output format.
+func newPrinter(format string) (printer, error) {
+	switch {
+	case format == "text", format == "json", format == "yaml":
+		return printer{format: format}, nil
+
+	case strings.HasPrefix(format, templatePrefix):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, templatePrefix))
+		if err != nil {
+			return printer{}, fmt.Errorf("invalid output template: %s", err.Error())
+		}
+		return printer{format: "template", template: tmpl}, nil
+
+	default:
+		return printer{}, fmt.Errorf("invalid output format %q", format)
+	}
+}
+
+// print writes the given value to w. The text function is used to render the
+// value for the text format, while the other formats render the value itself.
+func (p printer) print(w io.Writer, value interface{}, text func(w io.Writer) error) error {
+	switch p.format {
+	case "json":
+		body, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", body)
+		return err
+
+	case "yaml":
+		body, err := marshalYAML(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(body)
+		return err
+
+	case "template":
+		var buf bytes.Buffer
+		if err := p.template.Execute(&buf, value); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w, strings.TrimSuffix(buf.String(), "\n"))
+		return err
+
+	default:
+		return text(w)
+	}
+}
+
+// marshalYAML renders the given value as YAML. The value is first converted
+// to JSON so that the field names match the json struct tags used throughout
+// package cci.
+func marshalYAML(value interface{}) ([]byte, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(generic)
+}
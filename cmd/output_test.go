@@ -0,0 +1,92 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestPrinter(t *testing.T) {
+
+	build := cci.Build{
+		BuildURL:    "https://circleci.com/gh/alice/example/123",
+		BuildNum:    123,
+		VCSRevision: "abc123",
+	}
+
+	tests := []struct {
+		title  string
+		format string
+		output string
+		err    string
+	}{
+		{
+			title:  "text",
+			format: "text",
+			output: "https://circleci.com/gh/alice/example/123\n",
+		},
+		{
+			title:  "json",
+			format: "json",
+			output: `"build_num": 123,`,
+		},
+		{
+			title:  "yaml",
+			format: "yaml",
+			output: "build_num: 123\n",
+		},
+		{
+			title:  "template",
+			format: "template={{.BuildNum}} {{.VCSRevision}}",
+			output: "123 abc123\n",
+		},
+		{
+			title:  "template with trailing newline",
+			format: "template={{.BuildNum}}\n",
+			output: "123\n",
+		},
+		{
+			title:  "invalid template",
+			format: "template={{.BuildNum",
+			err:    "invalid output template: template: output:1: unclosed action",
+		},
+		{
+			title:  "unknown format",
+			format: "xml",
+			err:    `invalid output format "xml"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			out, err := newPrinter(test.format)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = out.print(&buf, build, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, build.BuildURL)
+				return err
+			})
+
+			require.NoError(t, err)
+			require.Contains(t, buf.String(), test.output)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+// optionalParam is a positional parameter that may be omitted, in which case
+// its value is an empty string. The cli package only provides required
+// positional parameters.
+type optionalParam struct {
+	Name  string
+	Usage string
+}
+
+func (f optionalParam) MainName() string {
+	return f.Name
+}
+
+func (f optionalParam) FullNames() []string {
+	return []string{f.Name}
+}
+
+func (f optionalParam) IsRequired() bool {
+	return false
+}
+
+func (f optionalParam) DeprecationStr() string {
+	return ""
+}
+
+func (f optionalParam) HasLeader() bool {
+	return false
+}
+
+func (f optionalParam) Default() interface{} {
+	return ""
+}
+
+func (f optionalParam) Parse(str string) (interface{}, error) {
+	return str, nil
+}
+
+func (f optionalParam) PlaceholderStr() string {
+	return f.Name
+}
+
+func (f optionalParam) DefaultStr() string {
+	return ""
+}
+
+func (f optionalParam) EnvVarStr() string {
+	return ""
+}
+
+func (f optionalParam) UsageStr() string {
+	return f.Usage
+}
@@ -0,0 +1,273 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	paramsFileFlag = flag.StringFlag{
+		Name:  "params-file",
+		Usage: "file of build parameters, in dotenv format, or JSON or YAML if the name ends in .json, .yml, or .yaml, may be given multiple times",
+	}
+	paramsFromEnvFlag = flag.StringFlag{
+		Name:  "params-from-env",
+		Usage: "send every environment variable with the given prefix as a build parameter, with the prefix removed, may be given multiple times",
+	}
+	allowEmptyParamsFlag = flag.BoolFlag{
+		Name:  "allow-empty-params",
+		Usage: "allow build parameters of the form KEY= to send an empty value",
+	}
+)
+
+// loadParams collects the build parameters from every source given on the
+// command line. When a key is given by more than one source, the value from
+// the later source below is used:
+//
+//   - environment variables matching --params-from-env, in the order given
+//   - files given by --params-file, in the order given
+//   - the given KEY=VALUE arguments, where KEY=@path reads the value from a
+//     file, KEY=- reads it from stdin, and KEY=@@VALUE sends the literal
+//     @VALUE
+//
+// Giving the same key twice within a single file, or within the arguments,
+// is an error. If there are no parameters, nil is returned.
+func loadParams(ctx cli.Context, args []string) (map[string]string, error) {
+	var (
+		allowEmpty = ctx.Bool(allowEmptyParamsFlag.Name)
+		sources    []map[string]string
+	)
+
+	if ctx.Has(paramsFromEnvFlag.Name) {
+		for _, prefix := range ctx.StringSlice(paramsFromEnvFlag.Name) {
+			if prefix == "" {
+				return nil, errors.New("invalid --params-from-env prefix, it must not be empty")
+			}
+			sources = append(sources, envParams(prefix, os.Environ()))
+		}
+	}
+
+	if ctx.Has(paramsFileFlag.Name) {
+		for _, path := range ctx.StringSlice(paramsFileFlag.Name) {
+			params, err := readParamsFile(path)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, params)
+		}
+	}
+
+	params, err := splitParams(args, allowEmpty)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both of these would read stdin, and only one of them can
+	for _, value := range params {
+		if value != "-" {
+			continue
+		}
+
+		stdin, err := tokenFromStdin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if stdin {
+			return nil, errors.New("the API token and a build parameter cannot both be read from stdin")
+		}
+		break
+	}
+
+	if err := readParamValues(params, os.Stdin); err != nil {
+		return nil, err
+	}
+
+	return mergeParams(append(sources, params)...), nil
+}
+
+// readParamValues replaces parameter values of the form @path with the
+// contents of the file at that path, and a value of - with the contents of
+// the given reader, which is normally stdin. As with $(cat path) in a shell,
+// trailing newlines are removed. Values starting with @@ are escaped literal
+// values, and only have the first @ removed.
+func readParamValues(params map[string]string, stdin io.Reader) error {
+	var (
+		keys      = make([]string, 0, len(params))
+		readStdin string
+	)
+
+	// Keys are read in order, so that errors are reported consistently
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var (
+			value = params[key]
+			body  []byte
+			err   error
+		)
+
+		switch {
+		case strings.HasPrefix(value, "@@"):
+			params[key] = value[1:]
+			continue
+
+		case value == "-":
+			if readStdin != "" {
+				return fmt.Errorf("build parameters %q and %q cannot both be read from stdin", readStdin, key)
+			}
+			readStdin = key
+
+			if body, err = ioutil.ReadAll(stdin); err != nil {
+				return fmt.Errorf("reading build parameter %q from stdin: %s", key, err.Error())
+			}
+
+		case strings.HasPrefix(value, "@"):
+			if body, err = ioutil.ReadFile(expandHome(value[1:])); err != nil {
+				return fmt.Errorf("reading build parameter %q: %s", key, err.Error())
+			}
+
+		default:
+			continue
+		}
+
+		params[key] = strings.TrimRight(string(body), "\r\n")
+	}
+
+	return nil
+}
+
+// envParams returns the variables in the given environment, of the form
+// NAME=VALUE, whose names start with the given prefix. The prefix is removed
+// from each name, and names that are not valid parameter keys are skipped.
+func envParams(prefix string, environ []string) map[string]string {
+	params := make(map[string]string)
+
+	for _, variable := range environ {
+		chunks := strings.SplitN(variable, "=", 2)
+		if len(chunks) != 2 || !strings.HasPrefix(chunks[0], prefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(chunks[0], prefix)
+		if !regexBuildVar.MatchString(key) {
+			continue
+		}
+
+		params[key] = chunks[1]
+	}
+
+	return params
+}
+
+// readParamsFile reads the build parameters in the file at the given path.
+// Files whose names end in .json, .yml, or .yaml must contain a single object
+// of keys and scalar values, and any other file is read as a dotenv file.
+func readParamsFile(path string) (map[string]string, error) {
+	body, err := ioutil.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading params file: %s", err.Error())
+	}
+
+	var params map[string]string
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yml", ".yaml":
+		// As JSON is a subset of YAML, both formats are parsed the same way
+		err = yaml.UnmarshalStrict(body, &params)
+	default:
+		params, err = parseDotenv(string(body))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid params file %s: %s", path, err.Error())
+	}
+
+	for key := range params {
+		if !regexBuildVar.MatchString(key) {
+			return nil, fmt.Errorf("invalid params file %s: invalid build parameter %q", path, key)
+		}
+	}
+
+	return params, nil
+}
+
+// parseDotenv parses the given dotenv file, made up of KEY=VALUE lines.
+// Blank lines and comments starting with # are ignored, and lines may start
+// with export. Values may be wrapped in single quotes, which are removed, or
+// double quotes, inside which escapes such as \n are interpreted.
+func parseDotenv(body string) (map[string]string, error) {
+	params := make(map[string]string)
+
+	for index, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		chunks := strings.SplitN(line, "=", 2)
+		if len(chunks) != 2 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", index+1)
+		}
+
+		key, value := strings.TrimSpace(chunks[0]), strings.TrimSpace(chunks[1])
+
+		if len(value) >= 2 {
+			switch {
+			case value[0] == '"' && value[len(value)-1] == '"':
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid quoted value", index+1)
+				}
+				value = unquoted
+
+			case value[0] == '\'' && value[len(value)-1] == '\'':
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		if _, found := params[key]; found {
+			return nil, fmt.Errorf("line %d: duplicate build parameter %q", index+1, key)
+		}
+
+		params[key] = value
+	}
+
+	return params, nil
+}
+
+// mergeParams combines the given sets of parameters, with values from later
+// sets replacing those from earlier ones. If there are no parameters, nil is
+// returned.
+func mergeParams(sources ...map[string]string) map[string]string {
+	var merged map[string]string
+
+	for _, params := range sources {
+		for key, value := range params {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
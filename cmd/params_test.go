@@ -0,0 +1,222 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadParamsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		title  string
+		name   string
+		body   string
+		params map[string]string
+		err    string
+	}{
+		{
+			title: "dotenv",
+			name:  "params.env",
+			body: "" +
+				"# Release details\n" +
+				"VERSION=1.2.3\n" +
+				"\n" +
+				"export DEPLOY = true\n" +
+				"NOTES=\"first line\\nsecond line\"\n" +
+				"LITERAL='$HOME \\n'\n" +
+				"EMPTY=\n",
+			params: map[string]string{
+				"VERSION": "1.2.3",
+				"DEPLOY":  "true",
+				"NOTES":   "first line\nsecond line",
+				"LITERAL": `$HOME \n`,
+				"EMPTY":   "",
+			},
+		},
+		{
+			title: "json",
+			name:  "params.json",
+			body:  `{"VERSION": "1.2.3", "DEPLOY": true, "COUNT": 3}`,
+			params: map[string]string{
+				"VERSION": "1.2.3",
+				"DEPLOY":  "true",
+				"COUNT":   "3",
+			},
+		},
+		{
+			title: "yaml",
+			name:  "params.YAML",
+			body:  "VERSION: 1.2.3\nNOTES: |\n  first line\n  second line\n",
+			params: map[string]string{
+				"VERSION": "1.2.3",
+				"NOTES":   "first line\nsecond line\n",
+			},
+		},
+		{
+			title: "dotenv duplicate",
+			name:  "duplicate.env",
+			body:  "VERSION=1.2.3\nVERSION=1.2.4\n",
+			err:   `line 2: duplicate build parameter "VERSION"`,
+		},
+		{
+			title: "yaml duplicate",
+			name:  "duplicate.yml",
+			body:  "VERSION: 1.2.3\nVERSION: 1.2.4\n",
+			err:   `key "VERSION" already set in map`,
+		},
+		{
+			title: "dotenv missing equals",
+			name:  "invalid.env",
+			body:  "VERSION\n",
+			err:   "line 1: expected KEY=VALUE",
+		},
+		{
+			title: "json nested value",
+			name:  "nested.json",
+			body:  `{"CONFIG": {"debug": true}}`,
+			err:   "cannot unmarshal !!map into string",
+		},
+		{
+			title: "invalid key",
+			name:  "key.env",
+			body:  "deploy.target=prod\n",
+			err:   `invalid build parameter "deploy.target"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("%d-%s", index, test.name))
+			require.NoError(t, ioutil.WriteFile(path, []byte(test.body), 0644))
+
+			params, err := readParamsFile(path)
+
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.params, params)
+		})
+	}
+}
+
+func TestReadParamValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	changelog := filepath.Join(dir, "CHANGELOG.md")
+	require.NoError(t, ioutil.WriteFile(changelog, []byte("- Fixed things\n- Broke others\n\n"), 0644))
+
+	tests := []struct {
+		title  string
+		params map[string]string
+		stdin  string
+		result map[string]string
+		err    string
+	}{
+		{
+			title:  "plain values",
+			params: map[string]string{"VERSION": "1.2.3"},
+			result: map[string]string{"VERSION": "1.2.3"},
+		},
+		{
+			title:  "file",
+			params: map[string]string{"CHANGELOG": "@" + changelog, "VERSION": "1.2.3"},
+			result: map[string]string{"CHANGELOG": "- Fixed things\n- Broke others", "VERSION": "1.2.3"},
+		},
+		{
+			title:  "escaped literal values",
+			params: map[string]string{"NOTIFY": "@@oncall", "LITERAL": "@@@" + changelog},
+			result: map[string]string{"NOTIFY": "@oncall", "LITERAL": "@@" + changelog},
+		},
+		{
+			title:  "stdin",
+			params: map[string]string{"CONFIG": "-"},
+			stdin:  "{\"debug\": true}\n",
+			result: map[string]string{"CONFIG": `{"debug": true}`},
+		},
+		{
+			title:  "empty stdin",
+			params: map[string]string{"CONFIG": "-"},
+			result: map[string]string{"CONFIG": ""},
+		},
+		{
+			title:  "stdin twice",
+			params: map[string]string{"B": "-", "A": "-"},
+			err:    `build parameters "A" and "B" cannot both be read from stdin`,
+		},
+		{
+			title:  "missing file",
+			params: map[string]string{"CHANGELOG": "@" + filepath.Join(dir, "missing")},
+			err:    `reading build parameter "CHANGELOG": open ` + filepath.Join(dir, "missing") + ": no such file or directory",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			err := readParamValues(test.params, strings.NewReader(test.stdin))
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.result, test.params)
+		})
+	}
+}
+
+func TestEnvParams(t *testing.T) {
+	environ := []string{
+		"HOME=/home/alice",
+		"CCI_PARAM_VERSION=1.2.3",
+		"CCI_PARAM_EMPTY=",
+		"CCI_PARAM_=skipped",
+		"CCI_PARAM_1ST=skipped",
+		"CCI_PARAMETER=skipped",
+	}
+
+	require.Equal(t, map[string]string{
+		"VERSION": "1.2.3",
+		"EMPTY":   "",
+	}, envParams("CCI_PARAM_", environ))
+}
+
+func TestMergeParams(t *testing.T) {
+	require.Nil(t, mergeParams())
+	require.Nil(t, mergeParams(nil, map[string]string{}))
+
+	merged := mergeParams(
+		map[string]string{"VERSION": "from env", "ENV": "env"},
+		map[string]string{"VERSION": "from file", "FILE": "file"},
+		map[string]string{"VERSION": "from args"},
+	)
+
+	require.Equal(t, map[string]string{
+		"VERSION": "from args",
+		"ENV":     "env",
+		"FILE":    "file",
+	}, merged)
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var pipelineFlag = flag.BoolFlag{
+	Name:  "pipeline",
+	Usage: "trigger a v2 pipeline with typed parameters, instead of a v1.1 build",
+}
+
+// triggerPipeline triggers a v2 pipeline on the given project and branch,
+// using the tag given on the command line and the given params. The build
+// and ref are those resolved from the command line, including from a build
+// URL, and are rejected as pipelines cannot be triggered on them.
+func triggerPipeline(ctx cli.Context, out printer, vcs string, username string, project string, build string, branch string, ref string, params []string) error {
+	var (
+		tag  = ctx.String(tagFlag.Name)
+		ssh  = ctx.Bool(sshFlag.Name)
+		wait = ctx.Bool(waitFlag.Name)
+	)
+
+	// Pipelines can only be triggered on the head of a branch or on a tag
+	if ref != "" || build != "" || ssh || (branch != "" && tag != "") {
+		return errors.New("invalid flag combination")
+	}
+
+	if wait {
+		return errors.New("waiting is not supported for pipelines")
+	}
+
+	// Pipeline parameters are typed, so only the KEY[:TYPE]=VALUE form is
+	// supported
+	if ctx.Has(paramsFileFlag.Name) || ctx.Has(paramsFromEnvFlag.Name) || ctx.Bool(allowEmptyParamsFlag.Name) {
+		return errors.New("--params-file, --params-from-env, and --allow-empty-params are not supported for pipelines")
+	}
+
+	pipelineParams, err := splitPipelineParams(params)
+	if err != nil {
+		return err
+	}
+
+	options := cci.TriggerPipelineOptions{
+		Branch:     branch,
+		Tag:        tag,
+		Parameters: pipelineParams,
+	}
+
+	// Print the request that would be sent, instead of sending it
+	if ctx.Bool(dryRunFlag.Name) {
+		return dryRunTrigger(ctx, out, describePipeline(branch, tag), func(client cci.Client) error {
+			_, err := client.TriggerPipelineContext(ctx.Context(), vcs, username, project, options)
+			return err
+		})
+	}
+
+	client, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := client.TriggerPipelineContext(ctx.Context(), vcs, username, project, options)
+	if err != nil {
+		return err
+	}
+
+	return out.print(ctx.App.Stdout, pipeline, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, pipeline.ID)
+		return err
+	})
+}
+
+// describePipeline returns a readable description of triggering a pipeline
+// on the given branch or tag.
+func describePipeline(branch string, tag string) string {
+	switch {
+	case tag != "":
+		return fmt.Sprintf("trigger pipeline on tag %s", tag)
+	case branch != "":
+		return fmt.Sprintf("trigger pipeline on branch %s", branch)
+	default:
+		return "trigger pipeline on default branch"
+	}
+}
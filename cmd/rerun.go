@@ -0,0 +1,226 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	pipelineNumberFlag = flag.StringFlag{
+		Name:  "pipeline-number",
+		Usage: "number of a v2 pipeline in the project",
+	}
+	jobFlag = flag.StringFlag{
+		Name:  "job",
+		Usage: "name of a job to rerun, may be given multiple times",
+	}
+	fromFailedFlag = flag.BoolFlag{
+		Name:  "from-failed",
+		Usage: "rerun only the failed jobs, and the jobs that depend on them",
+	}
+	sparseTreeFlag = flag.BoolFlag{
+		Name:  "sparse-tree",
+		Usage: "rerun only the given jobs, and not the jobs that depend on them",
+	}
+)
+
+// rerun is a workflow that was rerun, and the ID of the new workflow.
+type rerun struct {
+	WorkflowID    string   `json:"workflow_id"`
+	WorkflowName  string   `json:"workflow_name"`
+	NewWorkflowID string   `json:"new_workflow_id"`
+	Jobs          []string `json:"jobs,omitempty"`
+}
+
+func rerunCommand() cli.Command {
+	return cli.Command{
+		Name:  "rerun",
+		Usage: "Rerun a v2 workflow, or specific jobs in a pipeline",
+		Flags: append([]flag.Flag{
+			optionalProjectParam,
+			workflowFlag,
+			pipelineNumberFlag,
+			jobFlag,
+			fromFailedFlag,
+			sparseTreeFlag,
+			sshFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project  = ctx.String(optionalProjectParam.Name)
+				workflow = ctx.String(workflowFlag.Name)
+				number   = ctx.String(pipelineNumberFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+				jobs     []string
+			)
+
+			// The job flag may be given any number of times
+			if ctx.Has(jobFlag.Name) {
+				jobs = ctx.StringSlice(jobFlag.Name)
+			}
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			// Workflows are global, while pipeline numbers belong to a project,
+			// which is inferred from the git remote if it is not given
+			switch {
+			case workflow != "" && number == "" && project == "":
+			case workflow == "" && number != "":
+			default:
+				return errors.New("invalid flag combination")
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			var workflows []cci.Workflow
+
+			if workflow != "" {
+				resp, err := client.GetWorkflowContext(ctx.Context(), workflow)
+				if err != nil {
+					return err
+				}
+				workflows = []cci.Workflow{*resp}
+			} else {
+				projectVCS, projectUsername, projectName, err := resolveProject(ctx, project)
+				if err != nil {
+					return err
+				}
+
+				pipeline, err := client.GetPipelineByNumberContext(ctx.Context(), projectVCS, projectUsername, projectName, number)
+				if err != nil {
+					return err
+				}
+
+				if workflows, err = client.GetPipelineWorkflowsContext(ctx.Context(), pipeline.ID); err != nil {
+					return err
+				}
+			}
+
+			options := cci.RerunWorkflowOptions{
+				EnableSSH:  ctx.Bool(sshFlag.Name),
+				FromFailed: ctx.Bool(fromFailedFlag.Name),
+				SparseTree: ctx.Bool(sparseTreeFlag.Name),
+			}
+
+			reruns, err := rerunWorkflows(ctx.Context(), client, workflows, jobs, options)
+			if err != nil {
+				// Workflows that were already rerun are still listed, so that
+				// it is clear which ones are left
+				if len(reruns) != 0 {
+					_ = printReruns(ctx, out, reruns)
+				}
+				return err
+			}
+
+			return printReruns(ctx, out, reruns)
+		},
+	}
+}
+
+// printReruns prints the given rerun workflows.
+func printReruns(ctx cli.Context, out printer, reruns []rerun) error {
+	return out.print(ctx.App.Stdout, reruns, func(w io.Writer) error {
+		for _, rerun := range reruns {
+			line := fmt.Sprintf("rerunning workflow %s as %s", rerun.WorkflowName, rerun.NewWorkflowID)
+			if len(rerun.Jobs) != 0 {
+				line += fmt.Sprintf(" (jobs %s)", strings.Join(rerun.Jobs, ", "))
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rerunWorkflows reruns each of the given workflows. If job names are given,
+// only the workflows containing those jobs are rerun, and every name must
+// match a job in at least one workflow. If a workflow fails to be rerun, the
+// workflows that were rerun before it are returned along with the error.
+func rerunWorkflows(ctx context.Context, client cci.Client, workflows []cci.Workflow, jobs []string, options cci.RerunWorkflowOptions) ([]rerun, error) {
+	type target struct {
+		workflow cci.Workflow
+		ids      []string
+		names    []string
+	}
+
+	var targets []target
+
+	if len(jobs) == 0 {
+		for _, workflow := range workflows {
+			targets = append(targets, target{workflow: workflow})
+		}
+	} else {
+		found := make(map[string]bool, len(jobs))
+
+		for _, workflow := range workflows {
+			workflowJobs, err := client.GetWorkflowJobsContext(ctx, workflow.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			current := target{workflow: workflow}
+			for _, job := range workflowJobs {
+				for _, name := range jobs {
+					if job.Name == name {
+						current.ids = append(current.ids, job.ID)
+						current.names = append(current.names, job.Name)
+						found[name] = true
+					}
+				}
+			}
+
+			if len(current.ids) != 0 {
+				targets = append(targets, current)
+			}
+		}
+
+		for _, name := range jobs {
+			if !found[name] {
+				return nil, fmt.Errorf("no job named %q", name)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no workflows to rerun")
+	}
+
+	reruns := make([]rerun, 0, len(targets))
+
+	for _, target := range targets {
+		options.Jobs = target.ids
+
+		id, err := client.RerunWorkflowContext(ctx, target.workflow.ID, options)
+		if err != nil {
+			return reruns, err
+		}
+
+		reruns = append(reruns, rerun{
+			WorkflowID:    target.workflow.ID,
+			WorkflowName:  target.workflow.Name,
+			NewWorkflowID: id,
+			Jobs:          target.names,
+		})
+	}
+
+	return reruns, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestRerunWorkflows(t *testing.T) {
+	workflows := []cci.Workflow{
+		{ID: "w1", Name: "build"},
+		{ID: "w2", Name: "deploy"},
+	}
+
+	tests := []struct {
+		title   string
+		jobs    []string
+		options cci.RerunWorkflowOptions
+		bodies  map[string]string
+		fail    string
+		reruns  []rerun
+		err     string
+	}{
+		{
+			title: "every workflow",
+			bodies: map[string]string{
+				"w1": `{}`,
+				"w2": `{}`,
+			},
+			reruns: []rerun{
+				{WorkflowID: "w1", WorkflowName: "build", NewWorkflowID: "new-w1"},
+				{WorkflowID: "w2", WorkflowName: "deploy", NewWorkflowID: "new-w2"},
+			},
+		},
+		{
+			title:   "every workflow from failed",
+			options: cci.RerunWorkflowOptions{FromFailed: true},
+			bodies: map[string]string{
+				"w1": `{"from_failed": true}`,
+				"w2": `{"from_failed": true}`,
+			},
+			reruns: []rerun{
+				{WorkflowID: "w1", WorkflowName: "build", NewWorkflowID: "new-w1"},
+				{WorkflowID: "w2", WorkflowName: "deploy", NewWorkflowID: "new-w2"},
+			},
+		},
+		{
+			title:   "jobs in one workflow",
+			jobs:    []string{"test"},
+			options: cci.RerunWorkflowOptions{EnableSSH: true},
+			bodies: map[string]string{
+				"w1": `{"jobs": ["j2"], "enable_ssh": true}`,
+			},
+			reruns: []rerun{
+				{WorkflowID: "w1", WorkflowName: "build", NewWorkflowID: "new-w1", Jobs: []string{"test"}},
+			},
+		},
+		{
+			title:   "jobs across workflows",
+			jobs:    []string{"compile", "release"},
+			options: cci.RerunWorkflowOptions{SparseTree: true},
+			bodies: map[string]string{
+				"w1": `{"jobs": ["j1"], "sparse_tree": true}`,
+				"w2": `{"jobs": ["j4"], "sparse_tree": true}`,
+			},
+			reruns: []rerun{
+				{WorkflowID: "w1", WorkflowName: "build", NewWorkflowID: "new-w1", Jobs: []string{"compile"}},
+				{WorkflowID: "w2", WorkflowName: "deploy", NewWorkflowID: "new-w2", Jobs: []string{"release"}},
+			},
+		},
+		{
+			title: "failed rerun",
+			fail:  "w2",
+			bodies: map[string]string{
+				"w1": `{}`,
+			},
+			reruns: []rerun{
+				{WorkflowID: "w1", WorkflowName: "build", NewWorkflowID: "new-w1"},
+			},
+		},
+		{
+			title:   "jobs from failed",
+			jobs:    []string{"test"},
+			options: cci.RerunWorkflowOptions{FromFailed: true},
+			err:     "only one of from failed or jobs may be given",
+		},
+		{
+			title: "unknown job",
+			jobs:  []string{"test", "lint"},
+			err:   `no job named "lint"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			bodies := make(map[string]string)
+
+			client, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch path := strings.TrimPrefix(r.URL.Path, "/api/v2/workflow/"); path {
+				case "w1/job":
+					fmt.Fprint(w, `{"items": [{"id": "j1", "name": "compile"}, {"id": "j2", "name": "test"}]}`)
+				case "w2/job":
+					fmt.Fprint(w, `{"items": [{"id": "j3", "name": "compile-docs"}, {"id": "j4", "name": "release"}]}`)
+				default:
+					require.Equal(t, "POST", r.Method)
+
+					var body json.RawMessage
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+					id := strings.TrimSuffix(path, "/rerun")
+					if id == test.fail {
+						w.WriteHeader(http.StatusNotFound)
+						fmt.Fprint(w, `{"message": "Workflow not found"}`)
+						return
+					}
+					bodies[id] = string(body)
+
+					fmt.Fprintf(w, `{"workflow_id": "new-%s"}`, id)
+				}
+			})
+			defer done()
+
+			reruns, err := rerunWorkflows(context.Background(), client, workflows, test.jobs, test.options)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				require.Empty(t, bodies)
+				return
+			}
+
+			// The workflows after the failed one are not rerun
+			if test.fail != "" {
+				require.True(t, cci.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.reruns, reruns)
+
+			require.Len(t, bodies, len(test.bodies))
+			for id, body := range test.bodies {
+				require.JSONEq(t, body, bodies[id])
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	allProjectsFlag = flag.BoolFlag{
+		Name:  "all-projects",
+		Usage: "trigger every followed project",
+	}
+	projectGlobFlag = flag.StringFlag{
+		Name:  "project-glob",
+		Usage: "trigger every followed project matching the given glob, such as username/api-*",
+	}
+	yesFlag = flag.BoolFlag{
+		Name:  "yes",
+		Alias: "y",
+		Usage: "trigger the selected projects without asking for confirmation",
+	}
+)
+
+// triggerSelected triggers the given action on every followed project
+// matching the given glob, or on every followed project if the glob is empty.
+// The selected projects are listed, and must be confirmed unless yes is true.
+func triggerSelected(ctx cli.Context, out printer, glob string, yes bool, action action, description string, handler handler) error {
+	switch action {
+	case buildDefault, buildBranch, buildTag:
+	default:
+		return errors.New("--all-projects and --project-glob only support building the default branch, a branch, or a tag")
+	}
+
+	conf, err := loadSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Globs are qualified with the profile's org and VCS, just like project
+	// names are
+	if glob != "" {
+		glob = qualifyProject(conf, glob)
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid project glob %q", glob)
+		}
+	}
+
+	client, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	projects, err := client.ListProjectsContext(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectProjects(projects, glob)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		if glob == "" {
+			return errors.New("no followed projects")
+		}
+		return fmt.Errorf("no followed projects match %q", glob)
+	}
+
+	targets := make([]batchTarget, 0, len(selected))
+	for _, project := range selected {
+		targets = append(targets, batchTarget{
+			vcs:         project.VCSType,
+			username:    project.Username,
+			project:     project.Reponame,
+			description: description,
+			handler:     handler,
+		})
+	}
+
+	// The selection is listed on stderr, so that stdout only holds the results
+	fmt.Fprintf(os.Stderr, "Selected %d projects to %s:\n", len(targets), description)
+	for _, target := range targets {
+		fmt.Fprintf(os.Stderr, "  %s\n", cci.ProjectSlug(target.vcs, target.username, target.project))
+	}
+
+	if !yes {
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("refusing to trigger %d projects without confirmation, use --yes to skip it", len(targets))
+		}
+
+		confirmed, err := confirm(os.Stdin, os.Stderr, fmt.Sprintf("Trigger %d projects?", len(targets)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("aborted")
+		}
+	}
+
+	results := triggerTargets(ctx.Context(), client, targets, ctx.Int(concurrencyFlag.Name))
+
+	return printBatch(ctx, out, results)
+}
+
+// selectProjects returns the followed projects matching the given glob,
+// sorted by name. A glob of the form vcs/username/project is matched against
+// the project slug (either gh/username/project or github/username/project), a
+// glob of the form username/project against the project name, and any other
+// glob against the name of the repository alone. An empty glob matches every
+// project.
+func selectProjects(projects []cci.Project, glob string) ([]cci.Project, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid project glob %q", glob)
+	}
+
+	var selected []cci.Project
+	for _, project := range projects {
+		if !project.Following {
+			continue
+		}
+
+		var names []string
+		switch strings.Count(glob, "/") {
+		case 0:
+			names = []string{project.Reponame}
+		case 1:
+			names = []string{project.Username + "/" + project.Reponame}
+		default:
+			names = []string{
+				cci.ProjectSlug(project.VCSType, project.Username, project.Reponame),
+				project.VCSType + "/" + project.Username + "/" + project.Reponame,
+			}
+		}
+
+		for _, name := range names {
+			if matched, _ := path.Match(glob, name); matched || glob == "" {
+				selected = append(selected, project)
+				break
+			}
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return cci.ProjectSlug(selected[i].VCSType, selected[i].Username, selected[i].Reponame) <
+			cci.ProjectSlug(selected[j].VCSType, selected[j].Username, selected[j].Reponame)
+	})
+
+	return selected, nil
+}
+
+// confirm writes the given question to w, and reports whether the answer
+// read from r was yes.
+func confirm(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestSelectProjects(t *testing.T) {
+	projects := []cci.Project{
+		{VCSType: "github", Username: "alice", Reponame: "web", Following: true},
+		{VCSType: "github", Username: "alice", Reponame: "api-users", Following: true},
+		{VCSType: "bitbucket", Username: "alice", Reponame: "api-billing", Following: true},
+		{VCSType: "github", Username: "bob", Reponame: "api-search", Following: true},
+		{VCSType: "github", Username: "alice", Reponame: "api-legacy", Following: false},
+	}
+
+	tests := []struct {
+		title    string
+		glob     string
+		selected []string
+		err      string
+	}{
+		{
+			title:    "every project",
+			glob:     "",
+			selected: []string{"bb/alice/api-billing", "gh/alice/api-users", "gh/alice/web", "gh/bob/api-search"},
+		},
+		{
+			title:    "repository name",
+			glob:     "api-*",
+			selected: []string{"bb/alice/api-billing", "gh/alice/api-users", "gh/bob/api-search"},
+		},
+		{
+			title:    "project name",
+			glob:     "alice/api-*",
+			selected: []string{"bb/alice/api-billing", "gh/alice/api-users"},
+		},
+		{
+			title:    "project slug",
+			glob:     "gh/alice/*",
+			selected: []string{"gh/alice/api-users", "gh/alice/web"},
+		},
+		{
+			title:    "long vcs name",
+			glob:     "bitbucket/*/*",
+			selected: []string{"bb/alice/api-billing"},
+		},
+		{
+			title: "no matches",
+			glob:  "carol/*",
+		},
+		{
+			title: "invalid glob",
+			glob:  "alice/[api",
+			err:   `invalid project glob "alice/[api"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			selected, err := selectProjects(projects, test.glob)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var slugs []string
+			for _, project := range selected {
+				slugs = append(slugs, cci.ProjectSlug(project.VCSType, project.Username, project.Reponame))
+			}
+			require.Equal(t, test.selected, slugs)
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		title     string
+		answer    string
+		confirmed bool
+	}{
+		{
+			title:     "yes",
+			answer:    "yes\n",
+			confirmed: true,
+		},
+		{
+			title:     "y without newline",
+			answer:    " Y",
+			confirmed: true,
+		},
+		{
+			title:  "no",
+			answer: "n\n",
+		},
+		{
+			title:  "empty",
+			answer: "\n",
+		},
+		{
+			title: "eof",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var prompt bytes.Buffer
+
+			confirmed, err := confirm(strings.NewReader(test.answer), &prompt, "Trigger 3 projects?")
+			require.NoError(t, err)
+			require.Equal(t, test.confirmed, confirmed)
+			require.Equal(t, "Trigger 3 projects? [y/N] ", prompt.String())
+		})
+	}
+}
@@ -5,14 +5,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-func splitParams(args []string) (map[string]string, error) {
-	regexBuildVar := regexp.MustCompile("^[a-zA-Z_]+[a-zA-Z0-9_]*$")
-
+// splitParams parses build parameters of the form KEY=VALUE. Each key may
+// only be given once, and empty values are rejected unless allowEmpty is true,
+// as they are usually the result of an unset shell variable.
+func splitParams(args []string, allowEmpty bool) (map[string]string, error) {
 	params := make(map[string]string, len(args))
 
 	if len(args) == 0 {
@@ -31,12 +35,16 @@ func splitParams(args []string) (map[string]string, error) {
 			fallthrough
 		case chunks[0] == "":
 			fallthrough
-		case chunks[1] == "":
+		case chunks[1] == "" && !allowEmpty:
 			fallthrough
 		case !regexBuildVar.MatchString(chunks[0]):
 			return nil, fmt.Errorf("invalid build parameter %q", arg)
 
 		default:
+			if _, found := params[chunks[0]]; found {
+				return nil, fmt.Errorf("duplicate build parameter %q", chunks[0])
+			}
+
 			params[chunks[0]] = chunks[1]
 		}
 	}
@@ -44,7 +52,122 @@ func splitParams(args []string) (map[string]string, error) {
 	return params, nil
 }
 
+// splitPipelineParams parses v2 pipeline parameters of the form KEY=VALUE or
+// KEY:TYPE=VALUE. Untyped values are inferred to be a bool or an int where
+// possible, and a string otherwise.
+func splitPipelineParams(args []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(args))
+
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	for _, arg := range args {
+		chunks := strings.SplitN(arg, "=", 2)
+
+		for index, chunk := range chunks {
+			chunks[index] = strings.TrimSpace(chunk)
+		}
+
+		if len(chunks) != 2 || chunks[1] == "" {
+			return nil, fmt.Errorf("invalid pipeline parameter %q", arg)
+		}
+
+		matches := regexPipelineParam.FindStringSubmatch(chunks[0])
+		if matches == nil {
+			return nil, fmt.Errorf("invalid pipeline parameter %q", arg)
+		}
+
+		value, err := parsePipelineValue(matches[2], chunks[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline parameter %q: %s", arg, err.Error())
+		}
+
+		if _, found := params[matches[1]]; found {
+			return nil, fmt.Errorf("duplicate pipeline parameter %q", matches[1])
+		}
+
+		params[matches[1]] = value
+	}
+
+	return params, nil
+}
+
+// isParamArg reports whether the given argument is a build parameter of the
+// form KEY=VALUE, or a pipeline parameter of the form KEY:TYPE=VALUE, rather
+// than a project. Project names, slugs, and URLs never match, as their first
+// = (if any) always follows a slash or colon.
+func isParamArg(arg string) bool {
+	chunks := strings.SplitN(arg, "=", 2)
+	if len(chunks) != 2 {
+		return false
+	}
+
+	key := strings.TrimSpace(chunks[0])
+
+	return regexBuildVar.MatchString(key) || regexPipelineParam.MatchString(key)
+}
+
+// parsePipelineValue converts the given value into the given pipeline
+// parameter type, or infers the type if none is given.
+func parsePipelineValue(kind string, value string) (interface{}, error) {
+	switch kind {
+	case "string":
+		return value, nil
+
+	case "bool":
+		switch value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		default:
+			return nil, errors.New("value is not a bool")
+		}
+
+	case "int":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("value is not an int")
+		}
+		return number, nil
+
+	default:
+		if value == "true" || value == "false" {
+			return value == "true", nil
+		}
+		// Only values that are written exactly as the number would be are
+		// inferred to be ints, so that values such as 0123 or +1 keep their
+		// formatting
+		if number, err := strconv.Atoi(value); err == nil && strconv.Itoa(number) == value {
+			return number, nil
+		}
+		return value, nil
+	}
+}
+
+// splitProject splits the given project identifier into a vcs, username, and
+// project. The identifier may be a project name (username/project), a v2
+// project slug (gh/username/project, or circleci/org-id/project-id for
+// standalone projects), a CircleCI web URL, a GitHub, Bitbucket, or GitLab
+// repository URL, or a git remote URL.
 func splitProject(name string) (string, string, string, error) {
+	vcs, username, project, _, err := splitProjectBuild(name)
+	return vcs, username, project, err
+}
+
+// splitProjectBuild is like splitProject, but also returns the build number
+// implied by a CircleCI build or job URL, or an empty string if there is none.
+func splitProjectBuild(name string) (string, string, string, string, error) {
+	switch {
+	case strings.HasPrefix(name, "https://"), strings.HasPrefix(name, "http://"):
+		return splitWebURL(name)
+
+	case strings.Contains(name, ":"):
+		vcs, username, project, err := splitRemoteURL(name)
+		return vcs, username, project, "", err
+	}
+
 	chunks := strings.SplitN(name, "/", 3)
 
 	switch len(chunks) {
@@ -53,15 +176,110 @@ func splitProject(name string) (string, string, string, error) {
 		case "gh":
 			fallthrough
 		case "github":
-			return "github", chunks[1], chunks[2], nil
+			return "github", chunks[1], chunks[2], "", nil
 		case "bb":
 			fallthrough
 		case "bitbucket":
-			return "bitbucket", chunks[1], chunks[2], nil
+			return "bitbucket", chunks[1], chunks[2], "", nil
+		case "gl":
+			fallthrough
+		case "gitlab":
+			return "gitlab", chunks[1], chunks[2], "", nil
+		case "circleci":
+			// Standalone projects are identified by organization and project IDs
+			if isUUID(chunks[1]) && isUUID(chunks[2]) {
+				return "circleci", chunks[1], chunks[2], "", nil
+			}
 		}
 	case 2:
-		return "github", chunks[0], chunks[1], nil
+		return "github", chunks[0], chunks[1], "", nil
+	}
+
+	return "", "", "", "", fmt.Errorf("invalid project name %q", name)
+}
+
+// splitWebURL splits a GitHub, Bitbucket, or GitLab repository URL, or a CircleCI web
+// URL. CircleCI build URLs (https://circleci.com/gh/username/project/123) and
+// job URLs (.../jobs/123) also return the number of the build.
+func splitWebURL(name string) (string, string, string, string, error) {
+	invalid := fmt.Errorf("invalid project name %q", name)
+
+	parsed, err := url.Parse(name)
+	if err != nil {
+		return "", "", "", "", invalid
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	var vcs string
+	switch strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.") {
+	case "github.com":
+		vcs = "github"
+	case "bitbucket.org":
+		vcs = "bitbucket"
+	case "gitlab.com":
+		vcs = "gitlab"
+	}
+
+	// Repository URLs, such as https://github.com/username/project/tree/master
+	if vcs != "" {
+		if len(segments) < 2 {
+			return "", "", "", "", invalid
+		}
+		return vcs, segments[0], strings.TrimSuffix(segments[1], ".git"), "", nil
+	}
+
+	// Any other host is assumed to be CircleCI, or an enterprise instance of
+	// it. Newer URLs are prefixed with /pipelines, and their numbers are
+	// pipeline numbers rather than build numbers.
+	legacy := true
+	if len(segments) > 0 && segments[0] == "pipelines" {
+		segments, legacy = segments[1:], false
+	}
+
+	if len(segments) < 3 {
+		return "", "", "", "", invalid
+	}
+
+	vcs, username, project, _, err := splitProjectBuild(strings.Join(segments[:3], "/"))
+	if err != nil {
+		return "", "", "", "", invalid
+	}
+
+	var build string
+	for index, segment := range segments[3:] {
+		rest := segments[3+index:]
+
+		switch {
+		case legacy && index == 0 && isNumber(segment):
+			build = segment
+		case segment == "jobs" && len(rest) > 1 && isNumber(rest[1]):
+			build = rest[1]
+		}
 	}
 
-	return "", "", "", fmt.Errorf("invalid project name %q", name)
+	return vcs, username, project, build, nil
+}
+
+// isNumber reports whether the given string is a positive decimal number.
+func isNumber(value string) bool {
+	number, err := strconv.Atoi(value)
+	return err == nil && number > 0
 }
+
+// isUUID reports whether the given string is a UUID, such as the IDs of
+// standalone CircleCI organizations and projects.
+func isUUID(value string) bool {
+	return regexUUID.MatchString(value)
+}
+
+var regexBuildVar = regexp.MustCompile("^[a-zA-Z_]+[a-zA-Z0-9_]*$")
+
+var regexPipelineParam = regexp.MustCompile("^([a-zA-Z][a-zA-Z0-9_-]*)(?::(string|bool|int))?$")
+
+var regexUUID = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
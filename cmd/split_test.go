@@ -14,10 +14,11 @@ import (
 func TestSplitParams(t *testing.T) {
 
 	tests := []struct {
-		title  string
-		args   []string
-		params map[string]string
-		err    string
+		title      string
+		args       []string
+		allowEmpty bool
+		params     map[string]string
+		err        string
 	}{
 		{
 			title: "no params",
@@ -50,11 +51,7 @@ func TestSplitParams(t *testing.T) {
 				"key3=value3",
 				"key2=value4",
 			},
-			params: map[string]string{
-				"key1": "value1",
-				"key2": "value4",
-				"key3": "value3",
-			},
+			err: `duplicate build parameter "key2"`,
 		},
 		{
 			title: "blank param",
@@ -81,6 +78,22 @@ func TestSplitParams(t *testing.T) {
 			args:  []string{" key= "},
 			err:   `invalid build parameter " key= "`,
 		},
+		{
+			title:      "empty value allowed",
+			args:       []string{"key1=", " key2= ", "key3=value3"},
+			allowEmpty: true,
+			params: map[string]string{
+				"key1": "",
+				"key2": "",
+				"key3": "value3",
+			},
+		},
+		{
+			title:      "empty key with empty values allowed",
+			args:       []string{"="},
+			allowEmpty: true,
+			err:        `invalid build parameter "="`,
+		},
 		{
 			title: "single equals suffix",
 			args:  []string{"=value"},
@@ -144,7 +157,129 @@ func TestSplitParams(t *testing.T) {
 		name := fmt.Sprintf("Case #%d - %s", index, test.title)
 
 		t.Run(name, func(t *testing.T) {
-			actual, err := splitParams(test.args)
+			actual, err := splitParams(test.args, test.allowEmpty)
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			}
+
+			require.Equal(t, test.params, actual)
+		})
+	}
+}
+
+func TestSplitPipelineParams(t *testing.T) {
+
+	tests := []struct {
+		title  string
+		args   []string
+		params map[string]interface{}
+		err    string
+	}{
+		{
+			title: "no params",
+		},
+		{
+			title: "inferred types",
+			args: []string{
+				"name=value",
+				"deploy=true",
+				"skip=false",
+				"count=3",
+				"negative=-1",
+				"version=1.2",
+				"zip=0123",
+				"offset=+1",
+				"zero=0",
+			},
+			params: map[string]interface{}{
+				"name":     "value",
+				"deploy":   true,
+				"skip":     false,
+				"count":    3,
+				"negative": -1,
+				"version":  "1.2",
+				"zip":      "0123",
+				"offset":   "+1",
+				"zero":     0,
+			},
+		},
+		{
+			title: "explicit types",
+			args: []string{
+				"name:string=true",
+				"deploy:bool=true",
+				"count:int=3",
+				"id:string=123",
+			},
+			params: map[string]interface{}{
+				"name":   "true",
+				"deploy": true,
+				"count":  3,
+				"id":     "123",
+			},
+		},
+		{
+			title: "hyphenated key",
+			args:  []string{"deploy-env=staging"},
+			params: map[string]interface{}{
+				"deploy-env": "staging",
+			},
+		},
+		{
+			title: "whitespace",
+			args:  []string{"  count:int  =  3  "},
+			params: map[string]interface{}{
+				"count": 3,
+			},
+		},
+		{
+			title: "blank param",
+			args:  []string{""},
+			err:   `invalid pipeline parameter ""`,
+		},
+		{
+			title: "missing value",
+			args:  []string{"key="},
+			err:   `invalid pipeline parameter "key="`,
+		},
+		{
+			title: "missing key",
+			args:  []string{"=value"},
+			err:   `invalid pipeline parameter "=value"`,
+		},
+		{
+			title: "unknown type",
+			args:  []string{"key:float=1.5"},
+			err:   `invalid pipeline parameter "key:float=1.5"`,
+		},
+		{
+			title: "invalid bool",
+			args:  []string{"deploy:bool=yes"},
+			err:   `invalid pipeline parameter "deploy:bool=yes": value is not a bool`,
+		},
+		{
+			title: "invalid int",
+			args:  []string{"count:int=three"},
+			err:   `invalid pipeline parameter "count:int=three": value is not an int`,
+		},
+		{
+			title: "duplicate params",
+			args:  []string{"a=1", "a=2"},
+			err:   `duplicate pipeline parameter "a"`,
+		},
+		{
+			title: "duplicate params with types",
+			args:  []string{"a:int=1", "a:string=2"},
+			err:   `duplicate pipeline parameter "a"`,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			actual, err := splitPipelineParams(test.args)
 
 			if test.err != "" {
 				require.EqualError(t, err, test.err)
@@ -209,6 +344,46 @@ func TestSplitProject(t *testing.T) {
 			username: "bob",
 			project:  "example",
 		},
+		{
+			title:    "short gitlab vcs",
+			arg:      "gl/erin/example",
+			vcs:      "gitlab",
+			username: "erin",
+			project:  "example",
+		},
+		{
+			title:    "long gitlab vcs",
+			arg:      "gitlab/erin/example",
+			vcs:      "gitlab",
+			username: "erin",
+			project:  "example",
+		},
+		{
+			title:    "standalone project",
+			arg:      "circleci/8e4f1a3b-6c2d-4e5f-9a0b-1c2d3e4f5a6b/0f9e8d7c-6b5a-4938-a7b6-c5d4e3f2a1b0",
+			vcs:      "circleci",
+			username: "8e4f1a3b-6c2d-4e5f-9a0b-1c2d3e4f5a6b",
+			project:  "0f9e8d7c-6b5a-4938-a7b6-c5d4e3f2a1b0",
+		},
+		{
+			title: "standalone project without ids",
+			arg:   "circleci/frank/example",
+			err:   `invalid project name "circleci/frank/example"`,
+		},
+		{
+			title:    "circleci standalone project url",
+			arg:      "https://app.circleci.com/pipelines/circleci/8e4f1a3b-6c2d-4e5f-9a0b-1c2d3e4f5a6b/0f9e8d7c-6b5a-4938-a7b6-c5d4e3f2a1b0/12",
+			vcs:      "circleci",
+			username: "8e4f1a3b-6c2d-4e5f-9a0b-1c2d3e4f5a6b",
+			project:  "0f9e8d7c-6b5a-4938-a7b6-c5d4e3f2a1b0",
+		},
+		{
+			title:    "gitlab repository url",
+			arg:      "https://gitlab.com/erin/example/-/tree/main",
+			vcs:      "gitlab",
+			username: "erin",
+			project:  "example",
+		},
 		{
 			title: "unknown vcs",
 			arg:   "svn/carol/example",
@@ -228,6 +403,96 @@ func TestSplitProject(t *testing.T) {
 			username: "dave-user",
 			project:  "example_repo",
 		},
+		{
+			title:    "circleci project url",
+			arg:      "https://circleci.com/gh/alice/example",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "circleci build url",
+			arg:      "https://circleci.com/gh/alice/example/123",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "circleci pipelines url",
+			arg:      "https://app.circleci.com/pipelines/bitbucket/bob/example",
+			vcs:      "bitbucket",
+			username: "bob",
+			project:  "example",
+		},
+		{
+			title:    "circleci enterprise url",
+			arg:      "https://circleci.example.com/bb/bob/example/tree/master",
+			vcs:      "bitbucket",
+			username: "bob",
+			project:  "example",
+		},
+		{
+			title: "circleci url with unknown vcs",
+			arg:   "https://circleci.com/svn/carol/example",
+			err:   `invalid project name "https://circleci.com/svn/carol/example"`,
+		},
+		{
+			title: "circleci url without project",
+			arg:   "https://app.circleci.com/pipelines/github/carol",
+			err:   `invalid project name "https://app.circleci.com/pipelines/github/carol"`,
+		},
+		{
+			title:    "github repository url",
+			arg:      "https://github.com/alice/example",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "github repository url with path",
+			arg:      "https://www.github.com/alice/example/tree/master/cmd",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "bitbucket repository url",
+			arg:      "https://bitbucket.org/bob/example/src/master/",
+			vcs:      "bitbucket",
+			username: "bob",
+			project:  "example",
+		},
+		{
+			title: "github url without project",
+			arg:   "https://github.com/alice",
+			err:   `invalid project name "https://github.com/alice"`,
+		},
+		{
+			title:    "github https remote",
+			arg:      "https://github.com/alice/example.git",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "github ssh remote",
+			arg:      "git@github.com:alice/example.git",
+			vcs:      "github",
+			username: "alice",
+			project:  "example",
+		},
+		{
+			title:    "bitbucket ssh remote",
+			arg:      "ssh://git@bitbucket.org/bob/example.git",
+			vcs:      "bitbucket",
+			username: "bob",
+			project:  "example",
+		},
+		{
+			title: "unsupported remote",
+			arg:   "git@example.com:carol/example.git",
+			err:   `unsupported git remote "git@example.com:carol/example.git", only GitHub, Bitbucket, and GitLab remotes are supported`,
+		},
 	}
 
 	for index, test := range tests {
@@ -246,3 +511,94 @@ func TestSplitProject(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitProjectBuild(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		arg     string
+		project string
+		build   string
+	}{
+		{
+			title:   "project name",
+			arg:     "alice/example",
+			project: "example",
+		},
+		{
+			title:   "circleci project url",
+			arg:     "https://circleci.com/gh/alice/example",
+			project: "example",
+		},
+		{
+			title:   "circleci build url",
+			arg:     "https://circleci.com/gh/alice/example/123",
+			project: "example",
+			build:   "123",
+		},
+		{
+			title:   "circleci build url with fragment",
+			arg:     "https://circleci.com/gh/alice/example/123#tests/containers/0",
+			project: "example",
+			build:   "123",
+		},
+		{
+			title:   "circleci pipeline url",
+			arg:     "https://app.circleci.com/pipelines/github/alice/example/45",
+			project: "example",
+		},
+		{
+			title:   "circleci job url",
+			arg:     "https://app.circleci.com/pipelines/github/alice/example/45/workflows/5ba1cb40-4d94-4d5f-8ec6-d3ec5f9e6f0d/jobs/123",
+			project: "example",
+			build:   "123",
+		},
+		{
+			title:   "github url",
+			arg:     "https://github.com/alice/example/pull/123",
+			project: "example",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			_, _, project, build, err := splitProjectBuild(test.arg)
+
+			require.NoError(t, err)
+			require.Equal(t, test.project, project)
+			require.Equal(t, test.build, build)
+		})
+	}
+}
+
+func TestIsParamArg(t *testing.T) {
+
+	tests := []struct {
+		arg   string
+		param bool
+	}{
+		{arg: "DEPLOY=true", param: true},
+		{arg: "_PRIVATE=1", param: true},
+		{arg: "deploy-env=staging", param: true},
+		{arg: "deploy:bool=true", param: true},
+		{arg: "EMPTY=", param: true},
+		{arg: ""},
+		{arg: "."},
+		{arg: "example"},
+		{arg: "alice/example"},
+		{arg: "gh/alice/example"},
+		{arg: "https://circleci.com/gh/alice/example/123?utm_source=email"},
+		{arg: "git@github.com:alice/example.git"},
+		{arg: "deploy:float=1.5"},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.arg)
+
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.param, isParamArg(test.arg))
+		})
+	}
+}
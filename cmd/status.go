@@ -0,0 +1,214 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var (
+	pipelineIDParam = flag.StringParam{
+		Name: "pipeline-id",
+	}
+	watchFlag = flag.BoolFlag{
+		Name:  "watch",
+		Usage: "refresh the status until every workflow has settled",
+	}
+)
+
+// workflowGracePeriod is how long a pipeline can have no workflows before it
+// is assumed that it will never have any.
+const workflowGracePeriod = 2 * time.Minute
+
+// pipelineStatus is a pipeline, along with its workflows and their jobs.
+type pipelineStatus struct {
+	Pipeline  *cci.Pipeline    `json:"pipeline"`
+	Workflows []workflowStatus `json:"workflows"`
+}
+
+// workflowStatus is a workflow, along with its jobs.
+type workflowStatus struct {
+	cci.Workflow
+	Jobs []cci.Job `json:"jobs"`
+}
+
+func statusCommand() cli.Command {
+	return cli.Command{
+		Name:  "status",
+		Usage: "Show the workflows and jobs of a pipeline",
+		Flags: append([]flag.Flag{
+			pipelineIDParam,
+			watchFlag,
+			pollIntervalFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				id       = ctx.String(pipelineIDParam.Name)
+				watch    = ctx.Bool(watchFlag.Name)
+				interval = ctx.Duration(pollIntervalFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			// When watching a terminal, redraw the status on every refresh.
+			// Otherwise only the settled status is printed.
+			redraw := watch && out.format == "text" && ctx.IsTerminal()
+
+			for {
+				status, err := getPipelineStatus(ctx.Context(), client, id)
+				if err != nil {
+					return err
+				}
+
+				settled := status.settled(time.Now())
+
+				if redraw || !watch || settled {
+					if redraw {
+						// Clear the screen and move the cursor to the top left
+						fmt.Fprint(ctx.App.Stdout, "\033[H\033[2J")
+					}
+
+					err := out.print(ctx.App.Stdout, status, func(w io.Writer) error {
+						return status.render(w, client, time.Now())
+					})
+					if err != nil {
+						return err
+					}
+				}
+
+				if !watch || settled {
+					return nil
+				}
+
+				select {
+				case <-ctx.Context().Done():
+					return ctx.Context().Err()
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+}
+
+// getPipelineStatus fetches the given pipeline, along with all of its
+// workflows and their jobs.
+func getPipelineStatus(ctx context.Context, client cci.Client, id string) (*pipelineStatus, error) {
+	pipeline, err := client.GetPipelineContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows, err := client.GetPipelineWorkflowsContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := pipelineStatus{
+		Pipeline:  pipeline,
+		Workflows: make([]workflowStatus, 0, len(workflows)),
+	}
+
+	for _, workflow := range workflows {
+		jobs, err := client.GetWorkflowJobsContext(ctx, workflow.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		status.Workflows = append(status.Workflows, workflowStatus{workflow, jobs})
+	}
+
+	return &status, nil
+}
+
+// settled reports whether the pipeline will not progress any further without
+// intervention. This is the case once every workflow has either finished or
+// is on hold waiting for an approval, or if the pipeline failed to start.
+//
+// A pipeline may also never have any workflows, such as when none of them
+// run on its branch. Since workflows are only created shortly after the
+// pipeline, a pipeline without any is settled once it is older than
+// workflowGracePeriod.
+func (status pipelineStatus) settled(now time.Time) bool {
+	if status.Pipeline.State == "errored" {
+		return true
+	}
+
+	if len(status.Workflows) == 0 {
+		created := status.Pipeline.CreatedAt
+		return created != nil && now.Sub(*created) >= workflowGracePeriod
+	}
+
+	for _, workflow := range status.Workflows {
+		if !workflow.Finished() && workflow.Status != "on_hold" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// render writes the status as a tree of pipeline, workflows and jobs.
+func (status pipelineStatus) render(w io.Writer, client cci.Client, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "pipeline #%d\t%s\t\t%s\n", status.Pipeline.Number, status.Pipeline.State, status.Pipeline.ID)
+
+	for _, pipelineErr := range status.Pipeline.Errors {
+		fmt.Fprintf(tw, "  error: %s\n", pipelineErr.Message)
+	}
+
+	for index, workflow := range status.Workflows {
+		branch, indent := "├── ", "│   "
+		if index == len(status.Workflows)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\n", branch, workflow.Name, workflow.Status, duration(workflow.CreatedAt, workflow.StoppedAt, now), workflow.ID)
+
+		for index, job := range workflow.Jobs {
+			jobBranch := "├── "
+			if index == len(workflow.Jobs)-1 {
+				jobBranch = "└── "
+			}
+
+			fmt.Fprintf(tw, "%s%s%s\t%s\t%s\t%s\n", indent, jobBranch, job.Name, job.Status, duration(job.StartedAt, job.StoppedAt, now), client.JobURL(job))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// duration formats the time elapsed between start and stop, or between start
+// and now if stop is not known yet.
+func duration(start *time.Time, stop *time.Time, now time.Time) string {
+	if start == nil {
+		return "-"
+	}
+
+	end := now
+	if stop != nil {
+		end = *stop
+	}
+
+	return end.Sub(*start).Round(time.Second).String()
+}
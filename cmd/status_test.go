@@ -0,0 +1,114 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestPipelineStatusRender(t *testing.T) {
+	at := func(minutes int) *time.Time {
+		t := time.Date(2017, 10, 1, 12, minutes, 0, 0, time.UTC)
+		return &t
+	}
+
+	status := pipelineStatus{
+		Pipeline: &cci.Pipeline{
+			ID:     "5034460f",
+			Number: 25,
+			State:  "created",
+		},
+		Workflows: []workflowStatus{
+			{
+				Workflow: cci.Workflow{ID: "fda08377", Name: "build", Status: "success", CreatedAt: at(0), StoppedAt: at(3)},
+				Jobs: []cci.Job{
+					{Name: "compile", Status: "success", JobNumber: 101, ProjectSlug: "gh/alice/example", StartedAt: at(0), StoppedAt: at(1)},
+					{Name: "test", Status: "success", JobNumber: 102, ProjectSlug: "gh/alice/example", StartedAt: at(1), StoppedAt: at(3)},
+				},
+			},
+			{
+				Workflow: cci.Workflow{ID: "a9c4e1b2", Name: "deploy", Status: "on_hold", CreatedAt: at(0)},
+				Jobs: []cci.Job{
+					{Name: "hold", Type: "approval", Status: "on_hold"},
+				},
+			},
+		},
+	}
+
+	expected := "" +
+		"pipeline #25     created        5034460f\n" +
+		"├── build        success  3m0s  fda08377\n" +
+		"│   ├── compile  success  1m0s  https://circleci.com/gh/alice/example/101\n" +
+		"│   └── test     success  2m0s  https://circleci.com/gh/alice/example/102\n" +
+		"└── deploy       on_hold  5m0s  a9c4e1b2\n" +
+		"    └── hold     on_hold  -     \n"
+
+	var buf bytes.Buffer
+	err := status.render(&buf, cci.New("token"), *at(5))
+
+	require.NoError(t, err)
+	require.Equal(t, expected, buf.String())
+	require.True(t, status.settled(*at(5)))
+}
+
+func TestPipelineStatusSettled(t *testing.T) {
+	at := func(seconds int) *time.Time {
+		t := time.Date(2017, 10, 1, 12, 0, seconds, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		title     string
+		pipeline  cci.Pipeline
+		workflows []cci.Workflow
+		settled   bool
+	}{
+		{
+			title:    "errored pipeline",
+			pipeline: cci.Pipeline{State: "errored", CreatedAt: at(0)},
+			settled:  true,
+		},
+		{
+			title:    "new pipeline without workflows",
+			pipeline: cci.Pipeline{State: "created", CreatedAt: at(50)},
+		},
+		{
+			title:    "old pipeline without workflows",
+			pipeline: cci.Pipeline{State: "created", CreatedAt: at(0)},
+			settled:  true,
+		},
+		{
+			title:     "running workflow",
+			pipeline:  cci.Pipeline{State: "created", CreatedAt: at(0)},
+			workflows: []cci.Workflow{{Status: "success"}, {Status: "running"}},
+		},
+		{
+			title:     "finished and on hold workflows",
+			pipeline:  cci.Pipeline{State: "created", CreatedAt: at(0)},
+			workflows: []cci.Workflow{{Status: "failed"}, {Status: "on_hold"}},
+			settled:   true,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			status := pipelineStatus{Pipeline: &test.pipeline}
+			for _, workflow := range test.workflows {
+				status.Workflows = append(status.Workflows, workflowStatus{Workflow: workflow})
+			}
+
+			require.Equal(t, test.settled, status.settled(*at(120)))
+		})
+	}
+}
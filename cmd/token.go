@@ -0,0 +1,192 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/palantir/pkg/cli/flag"
+)
+
+var tokenFromFlag = flag.StringFlag{
+	Name:  "token-from",
+	Usage: "where to read the API token from, one of env:<name>, file:<path>, command:<command>, or stdin",
+}
+
+// tokenProvider obtains an API token. Errors returned by a provider must
+// never include the token itself, and the string form of a provider
+// describes where the token comes from without revealing it.
+type tokenProvider interface {
+	Token(ctx context.Context) (string, error)
+	String() string
+}
+
+// staticToken is a token that is already known, such as one given in the
+// config file.
+type staticToken struct {
+	token  string
+	source string
+}
+
+func (provider staticToken) Token(ctx context.Context) (string, error) {
+	return checkToken(provider, provider.token)
+}
+
+func (provider staticToken) String() string {
+	return provider.source
+}
+
+// envToken reads a token from the named environment variable.
+type envToken string
+
+func (provider envToken) Token(ctx context.Context) (string, error) {
+	token, found := os.LookupEnv(string(provider))
+	if !found {
+		return "", fmt.Errorf("no %s in working environment", string(provider))
+	}
+
+	return checkToken(provider, token)
+}
+
+func (provider envToken) String() string {
+	return "$" + string(provider)
+}
+
+// fileToken reads a token from the file at the given path. The file must
+// not be accessible by other users.
+type fileToken string
+
+func (provider fileToken) Token(ctx context.Context) (string, error) {
+	path := expandHome(string(provider))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("reading token file: %s", err.Error())
+	}
+
+	// Windows does not support unix permissions
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("token file %s is accessible by other users (mode %04o), restrict it with chmod 600", path, info.Mode().Perm())
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading token file: %s", err.Error())
+	}
+
+	return checkToken(provider, string(body))
+}
+
+func (provider fileToken) String() string {
+	return "file " + string(provider)
+}
+
+// commandToken runs the given shell command, such as a password manager, and
+// reads a token from its output. The command may prompt on stderr.
+type commandToken string
+
+func (provider commandToken) Token(ctx context.Context) (string, error) {
+	shell, arg := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, arg = "cmd", "/C"
+	}
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, shell, arg, string(provider))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	// The output of the command is deliberately left out of the error, as it
+	// may include the token
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token command %q failed: %s", string(provider), err.Error())
+	}
+
+	return checkToken(provider, stdout.String())
+}
+
+func (provider commandToken) String() string {
+	return fmt.Sprintf("command %q", string(provider))
+}
+
+// stdinToken reads a token from the given reader, which is normally stdin.
+type stdinToken struct {
+	reader io.Reader
+}
+
+func (provider stdinToken) Token(ctx context.Context) (string, error) {
+	body, err := ioutil.ReadAll(provider.reader)
+	if err != nil {
+		return "", fmt.Errorf("reading token from stdin: %s", err.Error())
+	}
+
+	return checkToken(provider, string(body))
+}
+
+func (provider stdinToken) String() string {
+	return "stdin"
+}
+
+// parseTokenProvider parses a token source of the form env:<name>,
+// file:<path>, command:<command>, or stdin.
+func parseTokenProvider(spec string) (tokenProvider, error) {
+	if spec == "stdin" {
+		return stdinToken{os.Stdin}, nil
+	}
+
+	chunks := strings.SplitN(spec, ":", 2)
+	if len(chunks) == 2 && chunks[1] != "" {
+		switch chunks[0] {
+		case "env":
+			return envToken(chunks[1]), nil
+		case "file":
+			return fileToken(chunks[1]), nil
+		case "command":
+			return commandToken(chunks[1]), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid token source %q, expected one of env:<name>, file:<path>, command:<command>, or stdin", spec)
+}
+
+// checkToken trims surrounding whitespace from the given token, and ensures
+// that what remains looks like a token.
+func checkToken(provider tokenProvider, token string) (string, error) {
+	token = strings.TrimSpace(token)
+
+	switch {
+	case token == "":
+		return "", fmt.Errorf("empty API token from %s", provider)
+	case strings.ContainsAny(token, " \t\r\n"):
+		return "", fmt.Errorf("invalid API token from %s: token contains whitespace", provider)
+	}
+
+	return token, nil
+}
+
+// expandHome replaces a leading ~ in the given path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home := homeDir()
+	if home == "" {
+		return path
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cci-trigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	privateFile := filepath.Join(dir, "private")
+	require.NoError(t, ioutil.WriteFile(privateFile, []byte("file-token\n"), 0600))
+
+	publicFile := filepath.Join(dir, "public")
+	require.NoError(t, ioutil.WriteFile(publicFile, []byte("file-token\n"), 0644))
+	require.NoError(t, os.Chmod(publicFile, 0644))
+
+	tests := []struct {
+		title    string
+		provider tokenProvider
+		token    string
+		err      string
+		unix     bool
+	}{
+		{
+			title:    "static token",
+			provider: staticToken{"static-token", "profile"},
+			token:    "static-token",
+		},
+		{
+			title:    "file token",
+			provider: fileToken(privateFile),
+			token:    "file-token",
+		},
+		{
+			title:    "file token readable by others",
+			provider: fileToken(publicFile),
+			err:      fmt.Sprintf("token file %s is accessible by other users (mode 0644), restrict it with chmod 600", publicFile),
+			unix:     true,
+		},
+		{
+			title:    "command token",
+			provider: commandToken("echo command-token"),
+			token:    "command-token",
+			unix:     true,
+		},
+		{
+			title:    "failed command",
+			provider: commandToken("echo secret-token; exit 3"),
+			err:      `token command "echo secret-token; exit 3" failed: exit status 3`,
+			unix:     true,
+		},
+		{
+			title:    "stdin token",
+			provider: stdinToken{strings.NewReader("  stdin-token\n")},
+			token:    "stdin-token",
+		},
+		{
+			title:    "empty token",
+			provider: stdinToken{strings.NewReader("\n")},
+			err:      "empty API token from stdin",
+		},
+		{
+			title:    "token with whitespace",
+			provider: stdinToken{strings.NewReader("secret token\n")},
+			err:      "invalid API token from stdin: token contains whitespace",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			if test.unix && runtime.GOOS == "windows" {
+				t.Skip("not supported on windows")
+			}
+
+			token, err := test.provider.Token(context.Background())
+
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.token, token)
+		})
+	}
+}
+
+func TestParseTokenProvider(t *testing.T) {
+
+	tests := []struct {
+		title    string
+		spec     string
+		provider tokenProvider
+		err      bool
+	}{
+		{
+			title:    "env",
+			spec:     "env:MY_TOKEN",
+			provider: envToken("MY_TOKEN"),
+		},
+		{
+			title:    "file",
+			spec:     "file:~/.circleci/token",
+			provider: fileToken("~/.circleci/token"),
+		},
+		{
+			title:    "command",
+			spec:     "command:pass show circleci",
+			provider: commandToken("pass show circleci"),
+		},
+		{
+			title:    "stdin",
+			spec:     "stdin",
+			provider: stdinToken{os.Stdin},
+		},
+		{
+			title: "missing value",
+			spec:  "file:",
+			err:   true,
+		},
+		{
+			title: "unknown source",
+			spec:  "keyring:circleci",
+			err:   true,
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			provider, err := parseTokenProvider(test.spec)
+
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.provider, provider)
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	old, found := os.LookupEnv("HOME")
+	if found {
+		defer os.Setenv("HOME", old)
+	} else {
+		defer os.Unsetenv("HOME")
+	}
+	os.Setenv("HOME", "/home/alice")
+
+	require.Equal(t, "/home/alice", expandHome("~"))
+	require.Equal(t, filepath.Join("/home/alice", ".circleci", "token"), expandHome("~/.circleci/token"))
+	require.Equal(t, "~alice/token", expandHome("~alice/token"))
+	require.Equal(t, "token", expandHome("token"))
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+var debugFlag = flag.BoolFlag{
+	Name:  "debug",
+	Usage: "log the headers and bodies of every API request to stderr, in addition to what --verbose logs",
+}
+
+// tracedHeaders are the request and response headers logged by --debug, in
+// the order that they are logged. Request ID headers are always logged.
+var tracedHeaders = []string{
+	"Accept",
+	"Content-Type",
+	"Circle-Token",
+	"Authorization",
+	"Retry-After",
+	"X-Ratelimit-Limit",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Reset",
+}
+
+// newTraceLogger returns a logger that writes each request to w, with every
+// line prefixed by the given name. Headers and bodies are only written if
+// debug is true.
+func newTraceLogger(w io.Writer, name string, debug bool) cci.Logger {
+	return func(entry cci.RequestLog) {
+		// Each entry is written at once, so that the entries of concurrent
+		// requests are not interleaved
+		var trace, buf bytes.Buffer
+		renderTrace(&trace, entry, debug)
+
+		for _, line := range strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n") {
+			fmt.Fprintf(&buf, "%s: %s\n", name, line)
+		}
+
+		w.Write(buf.Bytes())
+	}
+}
+
+// renderTrace writes the given request, and its response or error. Lines
+// describing the request are prefixed with > and the response with <.
+func renderTrace(w io.Writer, entry cci.RequestLog, debug bool) {
+	fmt.Fprintf(w, "> %s %s\n", entry.Method, entry.URL)
+	if debug {
+		renderHeaders(w, ">", entry.RequestHeader)
+		renderBody(w, ">", entry.RequestBody, entry.RequestBodyTruncated)
+	}
+
+	duration := entry.Duration.Round(time.Millisecond)
+	if entry.Err != nil {
+		fmt.Fprintf(w, "< failed after %s: %s\n", duration, entry.Err.Error())
+		return
+	}
+
+	fmt.Fprintf(w, "< %s (%s)\n", entry.Status, duration)
+
+	// Request IDs identify the request to CircleCI support
+	for _, name := range sortedNames(entry.ResponseHeader) {
+		if strings.HasSuffix(strings.ToLower(name), "request-id") {
+			fmt.Fprintf(w, "< %s: %s\n", name, entry.ResponseHeader.Get(name))
+		}
+	}
+
+	if debug {
+		renderHeaders(w, "<", entry.ResponseHeader)
+		renderBody(w, "<", entry.ResponseBody, entry.ResponseBodyTruncated)
+	}
+}
+
+// renderHeaders writes the values of the given header that are listed in
+// tracedHeaders.
+func renderHeaders(w io.Writer, prefix string, header http.Header) {
+	for _, name := range tracedHeaders {
+		for _, value := range header[name] {
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+// renderBody writes each line of the given body, noting if it was truncated.
+func renderBody(w io.Writer, prefix string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(body), "\n"), "\n") {
+		fmt.Fprintf(w, "%s %s\n", prefix, strings.TrimSuffix(line, "\r"))
+	}
+
+	if truncated {
+		fmt.Fprintf(w, "%s ... (truncated)\n", prefix)
+	}
+}
+
+// sortedNames returns the sorted names of the given header.
+func sortedNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
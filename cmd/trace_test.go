@@ -0,0 +1,98 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+func TestTraceLogger(t *testing.T) {
+	entry := cci.RequestLog{
+		Method: "POST",
+		URL:    "https://circleci.com/api/v1.1/project/github/alice/example/tree/master",
+		RequestHeader: http.Header{
+			"Accept":       {"application/json"},
+			"Content-Type": {"application/json"},
+			"Circle-Token": {"REDACTED"},
+			"User-Agent":   {"Go-http-client/1.1"},
+		},
+		RequestBody:           []byte(`{"build_parameters":{"A":"1"}}`),
+		Status:                "201 Created",
+		StatusCode:            201,
+		ResponseHeader:        http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"abc123"}},
+		ResponseBody:          []byte("{\n  \"build_num\": 123,\n"),
+		ResponseBodyTruncated: true,
+		Duration:              312400 * time.Microsecond,
+	}
+
+	failed := cci.RequestLog{
+		Method:   "GET",
+		URL:      "https://circleci.com/api/v1.1/projects",
+		Duration: 1500 * time.Millisecond,
+		Err:      errors.New("connection refused"),
+	}
+
+	tests := []struct {
+		title    string
+		entry    cci.RequestLog
+		debug    bool
+		expected string
+	}{
+		{
+			title: "verbose",
+			entry: entry,
+			expected: "" +
+				"cci-trigger: > POST https://circleci.com/api/v1.1/project/github/alice/example/tree/master\n" +
+				"cci-trigger: < 201 Created (312ms)\n" +
+				"cci-trigger: < X-Request-Id: abc123\n",
+		},
+		{
+			title: "debug",
+			entry: entry,
+			debug: true,
+			expected: "" +
+				"cci-trigger: > POST https://circleci.com/api/v1.1/project/github/alice/example/tree/master\n" +
+				"cci-trigger: > Accept: application/json\n" +
+				"cci-trigger: > Content-Type: application/json\n" +
+				"cci-trigger: > Circle-Token: REDACTED\n" +
+				"cci-trigger: > {\"build_parameters\":{\"A\":\"1\"}}\n" +
+				"cci-trigger: < 201 Created (312ms)\n" +
+				"cci-trigger: < X-Request-Id: abc123\n" +
+				"cci-trigger: < Content-Type: application/json\n" +
+				"cci-trigger: < {\n" +
+				"cci-trigger: <   \"build_num\": 123,\n" +
+				"cci-trigger: < ... (truncated)\n",
+		},
+		{
+			title: "failed",
+			entry: failed,
+			debug: true,
+			expected: "" +
+				"cci-trigger: > GET https://circleci.com/api/v1.1/projects\n" +
+				"cci-trigger: < failed after 1.5s: connection refused\n",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			newTraceLogger(&buf, "cci-trigger", test.debug)(test.entry)
+
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+
+	"github.com/joshdk/cci-trigger/cci"
+)
+
+// Exit codes used to report the outcome of a finished build.
+const (
+	exitFailed             = 2
+	exitCanceled           = 3
+	exitInfrastructureFail = 4
+	exitTimedOut           = 5
+	exitUnknownOutcome     = 6
+)
+
+var (
+	waitFlag = flag.BoolFlag{
+		Name:  "wait",
+		Usage: "wait for the build to finish, and exit with its outcome",
+	}
+	pollIntervalFlag = flag.DurationFlag{
+		Name:  "poll-interval",
+		Value: "10s",
+		Usage: "time to wait between checks on the build status",
+	}
+	buildNumParam = flag.StringParam{
+		Name: "build",
+	}
+)
+
+func waitCommand() cli.Command {
+	return cli.Command{
+		Name:  "wait",
+		Usage: "Wait for an existing build to finish, and exit with its outcome",
+		Flags: append([]flag.Flag{
+			projectParam,
+			buildNumParam,
+			pollIntervalFlag,
+		}, clientFlags...),
+		Action: func(ctx cli.Context) error {
+			var (
+				project  = ctx.String(projectParam.Name)
+				build    = ctx.String(buildNumParam.Name)
+				interval = ctx.Duration(pollIntervalFlag.Name)
+				output   = ctx.String(outputFlag.Name)
+			)
+
+			out, err := newPrinter(output)
+			if err != nil {
+				return err
+			}
+
+			projectVCS, projectUsername, projectName, err := resolveProject(ctx, project)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			resp, err := waitForBuild(ctx.Context(), ctx.App.Name, client, projectVCS, projectUsername, projectName, build, interval)
+			if resp == nil {
+				return err
+			}
+
+			// Print the finished build, even if it did not succeed
+			printErr := out.print(ctx.App.Stdout, resp, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, resp.BuildURL)
+				return err
+			})
+			if printErr != nil {
+				return printErr
+			}
+
+			return err
+		},
+	}
+}
+
+// waitForBuild polls the given build until it finishes, printing each status
+// transition to stderr. The finished build is returned, along with an error
+// carrying a distinct exit code if the build did not succeed.
+func waitForBuild(ctx context.Context, name string, client cci.Client, vcs string, username string, project string, build string, interval time.Duration) (*cci.Build, error) {
+	var lastStatus string
+
+	for {
+		resp, err := client.GetBuildContext(ctx, vcs, username, project, build)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Status != lastStatus {
+			fmt.Fprintf(os.Stderr, "%s: build #%s is %s\n", name, build, resp.Status)
+			lastStatus = resp.Status
+		}
+
+		if resp.Finished() {
+			return resp, outcomeError(build, resp.Outcome)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// outcomeError converts the outcome of a finished build into an error with a
+// matching exit code, or nil if the build succeeded.
+func outcomeError(build string, outcome string) error {
+	var code int
+
+	switch outcome {
+	case "success":
+		return nil
+	case "failed", "no_tests":
+		code = exitFailed
+	case "canceled":
+		code = exitCanceled
+	case "infrastructure_fail":
+		code = exitInfrastructureFail
+	case "timedout":
+		code = exitTimedOut
+	default:
+		code = exitUnknownOutcome
+	}
+
+	if outcome == "" {
+		outcome = "unknown"
+	}
+
+	return cli.WithExitCode(code, fmt.Errorf("build #%s finished with outcome %s", build, outcome))
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 Josh Komoroske. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutcomeError(t *testing.T) {
+
+	tests := []struct {
+		title   string
+		outcome string
+		code    int
+		err     string
+	}{
+		{
+			title:   "success",
+			outcome: "success",
+		},
+		{
+			title:   "failed",
+			outcome: "failed",
+			code:    exitFailed,
+			err:     "build #123 finished with outcome failed",
+		},
+		{
+			title:   "no tests",
+			outcome: "no_tests",
+			code:    exitFailed,
+			err:     "build #123 finished with outcome no_tests",
+		},
+		{
+			title:   "canceled",
+			outcome: "canceled",
+			code:    exitCanceled,
+			err:     "build #123 finished with outcome canceled",
+		},
+		{
+			title:   "infrastructure fail",
+			outcome: "infrastructure_fail",
+			code:    exitInfrastructureFail,
+			err:     "build #123 finished with outcome infrastructure_fail",
+		},
+		{
+			title:   "timed out",
+			outcome: "timedout",
+			code:    exitTimedOut,
+			err:     "build #123 finished with outcome timedout",
+		},
+		{
+			title: "missing outcome",
+			code:  exitUnknownOutcome,
+			err:   "build #123 finished with outcome unknown",
+		},
+	}
+
+	for index, test := range tests {
+		name := fmt.Sprintf("Case #%d - %s", index, test.title)
+
+		t.Run(name, func(t *testing.T) {
+			err := outcomeError("123", test.outcome)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, test.err)
+			require.Equal(t, test.code, err.(cli.ExitCoder).ExitCode())
+		})
+	}
+}